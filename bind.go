@@ -0,0 +1,153 @@
+package servicectx
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// BindErrors is a list of all errors occurred while binding properties into a struct
+type BindErrors []error
+
+func (e BindErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the list of errors
+func (e BindErrors) Unwrap() []error {
+	return e
+}
+
+// Is checks if any of the errors matches a target with errors.Is
+func (e BindErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first error matching a target with errors.As, so that errors.As(err, &parseErr) works on a Bind result
+func (e BindErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Bind fills a struct pointed by target with properties of a given service, according to the struct tags:
+//
+//	type BillingConfig struct {
+//		Timeout time.Duration `servicectx:"timeout" default:"3s"`
+//		URL     *url.URL      `servicectx:"url,required"`
+//		Tags    []string      `servicectx:"tags"`
+//		DB      struct {
+//			Host string `servicectx:"host"`
+//		} `servicectx:"db"`
+//	}
+//
+// The values are converted with the same parsers as Get (see RegisterParser).
+// A nested struct is bound with its tag as a prefix ("db-host" above), or without a prefix if it has no tag.
+// Fields with no tag or with a "-" tag are left intact, as well as the fields of missing properties with no default value.
+// All missing required properties and invalid values are reported at once in BindErrors.
+func (p Properties) Bind(serviceName string, target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return errors.New("servicectx: bind target must be a non-nil pointer to a struct")
+	}
+
+	var result BindErrors
+	p.bindStruct(serviceName, "", value.Elem(), &result)
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	return result
+}
+
+func (p Properties) bindStruct(serviceName, prefix string, target reflect.Value, errs *BindErrors) {
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag, hasTag := field.Tag.Lookup("servicectx")
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		if isNestedStruct(field.Type) {
+			nestedPrefix := prefix
+			if name != "" {
+				nestedPrefix = prefix + name + Separator
+			}
+
+			p.bindStruct(serviceName, nestedPrefix, target.Field(i), errs)
+			continue
+		}
+
+		if !hasTag || name == "" || field.PkgPath != "" {
+			continue
+		}
+
+		p.bindField(serviceName, prefix+name, options == "required", field, target.Field(i), errs)
+	}
+}
+
+func (p Properties) bindField(serviceName, prop string, required bool, field reflect.StructField, target reflect.Value, errs *BindErrors) {
+	values := p.GetAll(serviceName, prop)
+	if len(values) == 0 || values[0] == "" {
+		defaultValue, hasDefault := field.Tag.Lookup("default")
+		switch {
+		case hasDefault:
+			values = []string{defaultValue}
+		case required:
+			*errs = append(*errs, &ValidationError{serviceName, prop, "", "required property is missing"})
+			return
+		default:
+			return
+		}
+	}
+
+	parsed, err := parseValues(field.Type, values)
+	if err != nil {
+		*errs = append(*errs, &ParseError{
+			Service:  serviceName,
+			Property: prop,
+			Value:    strings.Join(values, ","),
+			Type:     field.Type.String(),
+			Err:      err,
+		})
+		return
+	}
+
+	if parsed != nil && target.CanSet() {
+		target.Set(reflect.ValueOf(parsed))
+	}
+}
+
+// checks if a struct type should be bound field by field, rather than parsed from a single property
+func isNestedStruct(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct || reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return false
+	}
+
+	parsers.RLock()
+	defer parsers.RUnlock()
+
+	_, ok := parsers.byType[typ]
+	return !ok
+}
@@ -0,0 +1,93 @@
+package servicectx
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testRetryConfig struct {
+	Count int           `servicectx:"count" default:"1"`
+	Delay time.Duration `servicectx:"delay"`
+}
+
+type testBillingConfig struct {
+	testRetryConfig
+	Timeout   time.Duration `servicectx:"timeout" default:"3s"`
+	URL       *url.URL      `servicectx:"url,required"`
+	Tags      []string      `servicectx:"tags"`
+	Since     time.Time     `servicectx:"since"`
+	Ignored   string        `servicectx:"-"`
+	Untagged  string
+	DB        testDBConfig `servicectx:"db"`
+	unexposed string       `servicectx:"unexposed"`
+}
+
+type testDBConfig struct {
+	Host string `servicectx:"host" default:"localhost"`
+	Port int    `servicectx:"port"`
+}
+
+func TestProperties_Bind(t *testing.T) {
+	props := New().
+		Set("billing", "url", "http://billing-v2").
+		Set("billing", "tags", "a,b").
+		Set("billing", "delay", "100ms").
+		Set("billing", "since", "2022-03-11T00:00:00Z").
+		Set("billing", "db-port", "5432").
+		Set("billing", "ignored", "value").
+		Set("billing", "untagged", "value").
+		Set("billing", "unexposed", "value")
+
+	cfg := testBillingConfig{Untagged: "initial"}
+	require.NoError(t, props.Bind("billing", &cfg))
+
+	require.Equal(t, 3*time.Second, cfg.Timeout)
+	require.Equal(t, "billing-v2", cfg.URL.Host)
+	require.Equal(t, []string{"a", "b"}, cfg.Tags)
+	require.Equal(t, time.Date(2022, 3, 11, 0, 0, 0, 0, time.UTC), cfg.Since)
+	require.Equal(t, 1, cfg.Count)
+	require.Equal(t, 100*time.Millisecond, cfg.Delay)
+	require.Equal(t, testDBConfig{Host: "localhost", Port: 5432}, cfg.DB)
+	require.Equal(t, "", cfg.Ignored)
+	require.Equal(t, "initial", cfg.Untagged)
+	require.Equal(t, "", cfg.unexposed)
+}
+
+func TestProperties_Bind_Errors(t *testing.T) {
+	require.Error(t, New().Bind("billing", nil))
+	require.Error(t, New().Bind("billing", testBillingConfig{}))
+
+	props := New().
+		Set("billing", "timeout", "3 seconds").
+		Set("billing", "db-port", "default")
+
+	var cfg testBillingConfig
+	err := props.Bind("billing", &cfg)
+
+	var bindErrors BindErrors
+	require.True(t, errors.As(err, &bindErrors))
+	require.Len(t, bindErrors, 3)
+
+	var parseErr *ParseError
+	require.True(t, errors.As(bindErrors[0], &parseErr))
+	require.Equal(t, "timeout", parseErr.Property)
+	require.Equal(t, &ValidationError{"billing", "url", "", "required property is missing"}, bindErrors[1])
+	require.True(t, errors.As(bindErrors[2], &parseErr))
+	require.Equal(t, "db-port", parseErr.Property)
+	require.Equal(t, "int", parseErr.Type)
+
+	// the errors can be found in the Bind result directly
+	parseErr = nil
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, "timeout", parseErr.Property)
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Equal(t, "url", validationErr.Property)
+	require.True(t, errors.Is(err, bindErrors[1]))
+
+	require.Contains(t, err.Error(), `servicectx: invalid property "url" of service "billing" with value "": required property is missing`)
+}
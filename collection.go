@@ -0,0 +1,113 @@
+package servicectx
+
+// Services returns the names of services having properties, in alphabetical order
+func (p Properties) Services() []string {
+	return sortedKeys(p.services)
+}
+
+// Range calls fn for each property in alphabetical order of services and property names, until fn returns false
+func (p Properties) Range(fn func(serviceName, prop string, values []string) bool) {
+	for _, serviceName := range p.Services() {
+		for _, prop := range sortedKeys(p.services[serviceName]) {
+			values, _ := p.values(serviceName, prop)
+			if !fn(serviceName, prop, values) {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns a copy of properties for which fn returns true
+func (p Properties) Filter(fn func(serviceName, prop string, values []string) bool) Properties {
+	result := New()
+
+	p.each(func(serviceName, prop string, values []string) {
+		if fn(serviceName, prop, values) {
+			result.setValues(serviceName, prop, values)
+			result.copyMetadata(p, serviceName, prop)
+		}
+	})
+
+	return result
+}
+
+// Delete removes a property along with its metadata (see SetHops). The receiver is modified and returned for chaining.
+func (p Properties) Delete(serviceName, prop string) Properties {
+	p.deleteProperty(p.serviceKey(serviceName), prop)
+
+	return p
+}
+
+// DeleteService removes all properties of a service. The receiver is modified and returned for chaining.
+func (p Properties) DeleteService(serviceName string) Properties {
+	serviceName = p.serviceKey(serviceName)
+	for prop := range p.services[serviceName] {
+		p.deleteProperty(serviceName, prop)
+	}
+
+	return p
+}
+
+// Equal checks if two sets of properties have the same values.
+// A service with no properties is equal to a missing one.
+func (p Properties) Equal(other Properties) bool {
+	return p.Diff(other).IsEmpty()
+}
+
+// Change describes a property which differs between two sets of properties
+type Change struct {
+	Service  string
+	Property string
+	// Old is empty for an added property
+	Old []string
+	// New is empty for a removed property
+	New []string
+}
+
+// Difference lists the changes between two sets of properties, sorted by services and property names
+type Difference struct {
+	Added   []Change
+	Removed []Change
+	Changed []Change
+}
+
+// IsEmpty checks if there are no changes
+func (d Difference) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff returns the changes needed to turn the properties into other properties
+func (p Properties) Diff(other Properties) Difference {
+	var diff Difference
+
+	p.each(func(serviceName, prop string, values []string) {
+		if otherValues, ok := other.values(serviceName, prop); !ok {
+			diff.Removed = append(diff.Removed, Change{Service: serviceName, Property: prop, Old: values})
+		} else if !equalValues(values, otherValues) {
+			diff.Changed = append(diff.Changed, Change{Service: serviceName, Property: prop, Old: values, New: otherValues})
+		}
+	})
+
+	other.each(func(serviceName, prop string, values []string) {
+		if _, ok := p.first(serviceName, prop); !ok {
+			diff.Added = append(diff.Added, Change{Service: serviceName, Property: prop, New: values})
+		}
+	})
+
+	return diff
+}
+
+// checks if two lists of values are equal
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,129 @@
+package servicectx
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestProperties_Services(t *testing.T) {
+	props := New().Set("b", "url", "http://b").Set("a", "url", "http://a").Set("my-service", "branch", "main")
+
+	require.Equal(t, []string{"a", "b", "myservice"}, props.Services())
+	require.Empty(t, New().Services())
+}
+
+func TestProperties_Range(t *testing.T) {
+	props := New().
+		Set("b", "url", "http://b").
+		Set("a", "version", "1").
+		Set("a", "branch", "main").
+		Add("a", "tag", "one").
+		Add("a", "tag", "two")
+
+	var visited []string
+	var tags []string
+	props.Range(func(serviceName, prop string, values []string) bool {
+		visited = append(visited, serviceName+"."+prop)
+		if prop == "tag" {
+			tags = values
+		}
+
+		return true
+	})
+
+	require.Equal(t, []string{"a.branch", "a.tag", "a.version", "b.url"}, visited)
+	require.Equal(t, []string{"one", "two"}, tags)
+
+	visited = nil
+	props.Range(func(serviceName, prop string, values []string) bool {
+		visited = append(visited, serviceName+"."+prop)
+
+		return len(visited) < 2
+	})
+	require.Equal(t, []string{"a.branch", "a.tag"}, visited)
+}
+
+func TestProperties_Filter(t *testing.T) {
+	props := New().Set("a", "url", "http://a").Set("a", "branch", "main").Set("b", "url", "http://b")
+
+	filtered := props.Filter(func(serviceName, prop string, values []string) bool {
+		return prop == "url"
+	})
+
+	require.Equal(t, New().Set("a", "url", "http://a").Set("b", "url", "http://b"), filtered)
+	require.True(t, props.HasProperty("a", "branch"))
+}
+
+func TestProperties_Delete(t *testing.T) {
+	props := New().
+		Set("my-service", "url", "http://a").
+		SetHops("my-service", "url", 1).
+		Set("my-service", "branch", "main").
+		Set("b", "url", "http://b")
+
+	props.Delete("my-service", "url")
+	require.Equal(t, New().Set("myservice", "branch", "main").Set("b", "url", "http://b"), props)
+
+	props.Set("my-service", "url", "http://b")
+	_, ok := props.Hops("my-service", "url")
+	require.False(t, ok, "metadata of a deleted property must not be restored")
+	props.Delete("my-service", "url")
+
+	props.Delete("my-service", "branch").Delete("b", "missing").Delete("missing", "url")
+	require.Equal(t, New().Set("b", "url", "http://b"), props)
+	require.False(t, props.HasService("myservice"))
+}
+
+func TestProperties_DeleteService(t *testing.T) {
+	props := New().Set("my-service", "url", "http://a").Set("b", "url", "http://b")
+
+	require.Equal(t, New().Set("b", "url", "http://b"), props.DeleteService("my-service"))
+}
+
+func TestProperties_Clone(t *testing.T) {
+	props := New().Set("a", "url", "http://a").Add("a", "tag", "one").Add("a", "tag", "two").SetHops("a", "tag", 1)
+	clone := props.Clone()
+	require.Equal(t, props, clone)
+
+	clone.Set("a", "url", "http://b").Add("a", "tag", "three").SetHops("a", "tag", 2)
+
+	require.Equal(t, "http://a", props.Get("a", "url", ""))
+	require.Equal(t, "http://b", clone.Get("a", "url", ""))
+	require.Equal(t, []string{"one", "two"}, props.GetAll("a", "tag"))
+	hops, _ := props.Hops("a", "tag")
+	require.Equal(t, 1, hops)
+}
+
+func TestProperties_Equal(t *testing.T) {
+	props := New().Set("a", "url", "http://a").Add("a", "tag", "one").Add("a", "tag", "two")
+
+	require.True(t, props.Equal(props.Clone()))
+	require.True(t, New().Equal(Properties{}))
+	require.True(t, New().Set("a", "url", "http://a").Delete("a", "url").Equal(New()))
+	require.False(t, props.Equal(New().Set("a", "url", "http://a").Add("a", "tag", "one")))
+	require.False(t, props.Equal(New()))
+}
+
+func TestProperties_Diff(t *testing.T) {
+	old := New().
+		Set("a", "url", "http://a").
+		Set("a", "branch", "main").
+		Add("b", "tag", "one")
+	updated := New().
+		Set("a", "url", "http://a").
+		Set("a", "version", "2").
+		Add("b", "tag", "one").
+		Add("b", "tag", "two")
+
+	require.Equal(
+		t,
+		Difference{
+			Added:   []Change{{Service: "a", Property: "version", New: []string{"2"}}},
+			Removed: []Change{{Service: "a", Property: "branch", Old: []string{"main"}}},
+			Changed: []Change{{Service: "b", Property: "tag", Old: []string{"one"}, New: []string{"one", "two"}}},
+		},
+		old.Diff(updated),
+	)
+
+	require.True(t, old.Diff(old.Clone()).IsEmpty())
+}
@@ -0,0 +1,74 @@
+package servicectx
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ConsumeAttribute is a metadata attribute marking a property as consumed by its target service (see SetConsumeAtTarget)
+const ConsumeAttribute = "consume"
+
+// the name of the current service set with SetLocalService
+var localService struct {
+	sync.RWMutex
+	value string
+}
+
+// SetLocalService sets the name of the current service, which consumes the properties addressed to it
+// and marked with SetConsumeAtTarget, so that they are not forwarded to downstream services.
+// An empty name (the default) means such properties are forwarded as any other ones.
+func SetLocalService(serviceName string) {
+	localService.Lock()
+	defer localService.Unlock()
+
+	localService.value = sanitizeServiceName(serviceName)
+}
+
+// returns the name of the current service set with SetLocalService
+func currentLocalService() string {
+	localService.RLock()
+	defer localService.RUnlock()
+
+	return localService.value
+}
+
+// SetConsumeAtTarget marks a property as meant only for the service named in its key:
+// once the property reaches that service, it is no longer forwarded (see SetLocalService).
+func (p Properties) SetConsumeAtTarget(serviceName, prop string) Properties {
+	p.setHidden(ConsumeAttribute, p.serviceKey(serviceName), prop, "true")
+
+	return p
+}
+
+// IsConsumedAtTarget checks if a property is marked with SetConsumeAtTarget
+func (p Properties) IsConsumedAtTarget(serviceName, prop string) bool {
+	return p.isConsumed(p.resolveService(serviceName, prop), prop)
+}
+
+// checks if a property of a service with an exact name is marked with SetConsumeAtTarget
+func (p Properties) isConsumed(serviceName, prop string) bool {
+	consume, _ := p.meta(serviceName, prop, ConsumeAttribute)
+	consumed, err := strconv.ParseBool(consume)
+
+	return consumed && err == nil
+}
+
+// Forwardable returns a copy of properties to be passed to downstream services:
+// without the expired ones, the ones with no hops left, and the ones consumed by the current service.
+// The sources of properties are not forwarded either. All functions injecting properties into carriers use it.
+func (p Properties) Forwardable() Properties {
+	result := p.withoutExpired(time.Now(), false)
+
+	localService := currentLocalService()
+
+	result.each(func(serviceName, prop string, _ []string) {
+		delete(result.sources, propertyKey{serviceName, prop})
+
+		if localService != "" && serviceName == localService && result.isConsumed(serviceName, prop) {
+			result.deleteProperty(serviceName, prop)
+		}
+	})
+
+	return result
+}
@@ -0,0 +1,87 @@
+package servicectx
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestProperties_SetConsumeAtTarget(t *testing.T) {
+	props := New().Set("api", "url", "http://api").SetConsumeAtTarget("api", "url")
+
+	require.True(t, props.IsConsumedAtTarget("api", "url"))
+	require.False(t, props.IsConsumedAtTarget("api", "branch"))
+	require.False(t, props.HasProperty("api", "url.consume"), "metadata must not be visible as a property")
+	require.Equal(t, map[string][]string{"x-service-api-url": {"http://api"}, "x-service-api-url.consume": {"true"}}, props.Entries())
+}
+
+func TestProperties_Forwardable(t *testing.T) {
+	props := New().
+		Set("api", "url", "http://api").
+		SetConsumeAtTarget("api", "url").
+		SetHops("api", "url", 2).
+		Set("api", "branch", "main").
+		Set("billing", "url", "http://billing").
+		SetConsumeAtTarget("billing", "url").
+		Set("billing", "debug", "true").
+		SetExpiry("billing", "debug", time.Now().Add(-time.Minute))
+
+	// the current service is unknown, so nothing is consumed
+	require.Equal(
+		t,
+		New().
+			Set("api", "url", "http://api").
+			SetConsumeAtTarget("api", "url").
+			SetHops("api", "url", 2).
+			Set("api", "branch", "main").
+			Set("billing", "url", "http://billing").
+			SetConsumeAtTarget("billing", "url"),
+		props.Forwardable(),
+	)
+
+	SetLocalService("api")
+	defer SetLocalService("")
+
+	require.Equal(
+		t,
+		New().
+			Set("api", "branch", "main").
+			Set("billing", "url", "http://billing").
+			SetConsumeAtTarget("billing", "url"),
+		props.Forwardable(),
+	)
+
+	// the original properties are intact
+	require.Equal(t, "http://api", props.Get("api", "url", ""))
+}
+
+func TestProperties_Forwardable_WholeService(t *testing.T) {
+	SetLocalService("api")
+	defer SetLocalService("")
+
+	props := New().Set("api", "url", "http://api").SetConsumeAtTarget("api", "url")
+
+	require.Equal(t, New(), props.Forwardable())
+}
+
+func TestInjectIntoHeaders_ConsumeAtTarget(t *testing.T) {
+	headers := http.Header{}
+	New().
+		Set("api", "url", "http://api").
+		SetConsumeAtTarget("api", "url").
+		Set("billing", "url", "http://billing").
+		InjectIntoHeaders(headers)
+
+	SetLocalService("api")
+	defer SetLocalService("")
+
+	// the property is available to its target service...
+	props := FromHeaders(headers)
+	require.Equal(t, "http://api", props.Get("api", "url", ""))
+
+	// ...but is not forwarded any further
+	headers = http.Header{}
+	props.InjectIntoHeaders(headers)
+	require.Equal(t, http.Header{"X-Service-Billing-Url": {"http://billing"}}, headers)
+}
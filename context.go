@@ -9,11 +9,52 @@ import (
 
 type contextKey string
 
-const contextKeyOptions = contextKey("servicectx")
+const (
+	contextKeyOptions     = contextKey("servicectx")
+	contextKeyPassThrough = contextKey("servicectx-passthrough")
+)
 
-// FromContext returns properties from context.
+// FromContext returns a copy of properties from context, so that it can be modified without affecting
+// other users of the same context.
 // If there are no properties in the context, an empty usable instance is returned.
 func FromContext(ctx context.Context) Properties {
+	return fromContext(ctx).Clone()
+}
+
+// InjectIntoContext adds a snapshot of properties to the context.
+// Subsequent modifications of the properties are not visible through the context.
+func (p Properties) InjectIntoContext(ctx context.Context) context.Context {
+	return withProperties(ctx, p.Clone())
+}
+
+// InjectIntoContextFromRequest parses properties from request and adds them into context.
+// In PassThrough mode, the properties are not visible through FromContext, but are passed on by InjectIntoHeadersFromContext.
+func InjectIntoContextFromRequest(ctx context.Context, req *http.Request, opts ...RequestOption) context.Context {
+	return injectIntoContextFromRequest(ctx, req, DefaultFormat, opts)
+}
+
+// parses properties of a given format from request and adds them into context
+func injectIntoContextFromRequest(ctx context.Context, req *http.Request, format Format, opts []RequestOption) context.Context {
+	if currentMode() == PassThrough {
+		ctx = context.WithValue(ctx, contextKeyPassThrough, fromRequest(req, format, extractEntries, opts))
+	}
+
+	return withProperties(ctx, FromRequestWithFormat(req, format, opts...))
+}
+
+// WithProperty returns a derived context holding the properties of a parent context and a given property
+func WithProperty(ctx context.Context, serviceName, prop, value string) context.Context {
+	return withProperties(ctx, FromContext(ctx).Set(serviceName, prop, value))
+}
+
+// WithProperties returns a derived context holding the properties of a parent context merged with given properties.
+// The given properties have a priority over the parent ones.
+func WithProperties(ctx context.Context, props Properties) context.Context {
+	return withProperties(ctx, FromContext(ctx).Merge(props))
+}
+
+// returns properties stored in the context as is; they must never be modified
+func fromContext(ctx context.Context) Properties {
 	props, ok := ctx.Value(contextKeyOptions).(Properties)
 	if ok {
 		return props
@@ -22,17 +63,22 @@ func FromContext(ctx context.Context) Properties {
 	return New()
 }
 
-// InjectIntoContext adds properties to the context
-func (p Properties) InjectIntoContext(ctx context.Context) context.Context {
-	return context.WithValue(ctx, contextKeyOptions, p)
-}
-
-// InjectIntoContextFromRequest parses properties from request and adds them into context
-func InjectIntoContextFromRequest(ctx context.Context, req *http.Request) context.Context {
-	return FromRequest(req).InjectIntoContext(ctx)
+// stores properties in the context as is; they must never be modified afterwards
+func withProperties(ctx context.Context, props Properties) context.Context {
+	return context.WithValue(ctx, contextKeyOptions, props)
 }
 
 // InjectIntoHeadersFromContext adds properties from context into http.Header
 func InjectIntoHeadersFromContext(ctx context.Context, header http.Header) {
-	FromContext(ctx).InjectIntoHeaders(header)
+	outgoingFromContext(ctx).InjectIntoHeaders(header)
+}
+
+// returns properties from context to be passed to downstream services,
+// including the ones received in PassThrough mode
+func outgoingFromContext(ctx context.Context) Properties {
+	if passThrough, ok := ctx.Value(contextKeyPassThrough).(Properties); ok {
+		return passThrough.Clone().Merge(fromContext(ctx))
+	}
+
+	return FromContext(ctx)
 }
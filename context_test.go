@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/stretchr/testify/require"
 	"net/http"
+	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -11,7 +13,7 @@ func TestFromContext(t *testing.T) {
 	emptyCtx := context.Background()
 	require.Equal(
 		t,
-		Properties{},
+		New(),
 		FromContext(emptyCtx),
 		"an empty struct must be returned from an empty context",
 	)
@@ -26,14 +28,9 @@ func TestProperties_InjectIntoContext(t *testing.T) {
 
 	require.Equal(
 		t,
-		Properties{
-			"a": Values{
-				"property": "value-a",
-			},
-			"b": Values{
-				"property": "value-b",
-			},
-		},
+		New().
+			Set("a", "property", "value-a").
+			Set("b", "property", "value-b"),
 		FromContext(ctx),
 		"properties must be successfully added to and retrieved from the context",
 	)
@@ -61,18 +58,66 @@ func Test_InjectIntoContextFromRequest(t *testing.T) {
 	ctx := InjectIntoContextFromRequest(context.Background(), req)
 	require.Equal(
 		t,
-		Properties{
-			"a": Values{
-				"option": "value-a",
-			},
-			"b": Values{
-				"option": "value-b",
-			},
-			"c": Values{
-				"option": "value-c",
-			},
-		},
+		New().
+			Set("a", "option", "value-a").
+			Set("b", "option", "value-b").
+			Set("c", "option", "value-c"),
 		FromContext(ctx),
 		"properties must be parsed from http.Header",
 	)
 }
+
+func TestFromContext_Immutable(t *testing.T) {
+	props := New().Set("a", "property", "value-a")
+	ctx := props.InjectIntoContext(context.Background())
+
+	props.Set("a", "property", "modified")
+	require.Equal(t, "value-a", FromContext(ctx).Get("a", "property", ""), "properties must be copied into context")
+
+	FromContext(ctx).Set("a", "property", "modified")
+	require.Equal(t, "value-a", FromContext(ctx).Get("a", "property", ""), "properties must be copied from context")
+}
+
+func TestWithProperty(t *testing.T) {
+	parent := WithProperty(context.Background(), "a", "property", "value-a")
+	child := WithProperty(parent, "b", "property", "value-b")
+	child = WithProperties(child, New().Set("a", "property", "new-value-a").Set("c", "property", "value-c"))
+
+	require.Equal(t, New().Set("a", "property", "value-a"), FromContext(parent))
+	require.Equal(
+		t,
+		New().
+			Set("a", "property", "new-value-a").
+			Set("b", "property", "value-b").
+			Set("c", "property", "value-c"),
+		FromContext(child),
+	)
+}
+
+func TestWithProperty_Concurrent(t *testing.T) {
+	ctx := WithProperty(context.Background(), "a", "property", "value")
+
+	indexes := make([]string, 10)
+	properties := make([]string, 10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			childCtx := WithProperty(ctx, "a", "index", strconv.Itoa(i))
+			FromContext(ctx).Set("a", "property", "modified")
+
+			indexes[i] = FromContext(childCtx).Get("a", "index", "")
+			properties[i] = FromContext(childCtx).Get("a", "property", "")
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i := 0; i < 10; i++ {
+		require.Equal(t, strconv.Itoa(i), indexes[i])
+		require.Equal(t, "value", properties[i])
+	}
+}
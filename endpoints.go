@@ -0,0 +1,206 @@
+package servicectx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// UrlVersionPlaceholder is a part of URL to be replaced with a version
+const UrlVersionPlaceholder = "$version"
+
+// the branch set with SetDefaultBranch
+var defaultBranch = struct {
+	sync.RWMutex
+	value string
+}{
+	value: "main",
+}
+
+// SetDefaultBranch sets a branch replacing a branch placeholder in the registered URLs
+// when neither a branch property nor Endpoint.Branch is set. It's "main" by default.
+func SetDefaultBranch(branch string) {
+	defaultBranch.Lock()
+	defer defaultBranch.Unlock()
+
+	defaultBranch.value = branch
+}
+
+// returns the branch set with SetDefaultBranch
+func currentDefaultBranch() string {
+	defaultBranch.RLock()
+	defer defaultBranch.RUnlock()
+
+	return defaultBranch.value
+}
+
+var (
+	// ErrUnknownEndpoint is returned by Resolve for a service not registered with Register and having no "url" property
+	ErrUnknownEndpoint = errors.New("servicectx: unknown service endpoint")
+	// ErrInvalidEndpoint is returned by Resolve when a resolved URL is invalid
+	ErrInvalidEndpoint = errors.New("servicectx: invalid service endpoint")
+)
+
+// Endpoint is a default address of a service
+type Endpoint struct {
+	// URL is a base URL of the service, optionally with branch and version placeholders, e.g. "http://billing-$branch:8080/$version"
+	URL string
+	// Branch replaces a branch placeholder when there is no "branch" property; the one set with SetDefaultBranch is used if empty
+	Branch string
+	// Version replaces a version placeholder when there is no "version" property
+	Version string
+}
+
+// the endpoints registered with Register
+var endpoints = struct {
+	sync.RWMutex
+	byService map[string]Endpoint
+}{
+	byService: map[string]Endpoint{},
+}
+
+// Register sets a default base URL of a service, e.g. Register("billing", "http://billing-$branch:8080")
+func Register(serviceName, baseURL string) {
+	RegisterEndpoint(serviceName, Endpoint{URL: baseURL})
+}
+
+// RegisterEndpoint sets a default address of a service
+func RegisterEndpoint(serviceName string, endpoint Endpoint) {
+	endpoints.Lock()
+	defer endpoints.Unlock()
+
+	endpoints.byService[sanitizeServiceName(serviceName)] = endpoint
+}
+
+// returns an endpoint registered with RegisterEndpoint
+func registeredEndpoint(serviceName string) (Endpoint, bool) {
+	endpoints.RLock()
+	defer endpoints.RUnlock()
+
+	endpoint, ok := endpoints.byService[sanitizeServiceName(serviceName)]
+	return endpoint, ok
+}
+
+// Resolve returns a URL of a service, overridden by the properties from the context. The properties are applied in order:
+//
+//  1. "url" replaces the registered URL altogether;
+//  2. otherwise, "branch" and "version" replace the placeholders in the registered URL
+//     (Endpoint.Branch or the one set with SetDefaultBranch, and Endpoint.Version are used by default);
+//     a branch consisting of anything but [a-z0-9-] after normalization (see ReplaceUrlBranch) results in ErrInvalidEndpoint,
+//     and the version is escaped;
+//  3. "port" replaces the port of the resulting URL.
+//
+// If a fallback is set with SetFallback, and the overridden branch is unreachable, the URL of the default branch is returned.
+// If the context is done before the branch is probed, its error is returned.
+func Resolve(ctx context.Context, serviceName string) (*url.URL, error) {
+	return FromContext(ctx).resolveWithFallback(ctx, serviceName)
+}
+
+// Resolve returns a URL of a service registered with Register, overridden by the properties (see the Resolve function)
+func (p Properties) Resolve(serviceName string) (*url.URL, error) {
+	return p.resolveWithFallback(context.Background(), serviceName)
+}
+
+// resolves a URL of a service, and falls back to the default branch if the overridden one is unreachable
+func (p Properties) resolveWithFallback(ctx context.Context, serviceName string) (*url.URL, error) {
+	// the branch is validated before probing, as an invalid one results in an error
+	resolved, err := p.resolve(serviceName, false)
+	if err != nil {
+		return nil, err
+	}
+
+	fallback := currentFallback()
+	if _, hasURL := p.Lookup(serviceName, "url"); fallback == nil || hasURL {
+		return resolved, nil
+	}
+
+	if _, hasBranch := p.Lookup(serviceName, "branch"); !hasBranch {
+		return resolved, nil
+	}
+
+	defaultURL, err := p.resolve(serviceName, true)
+	if err != nil || defaultURL.String() == resolved.String() {
+		return resolved, nil
+	}
+
+	if err := fallback.check(ctx, resolved); err != nil {
+		// the request is canceled, which says nothing about the branch
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if fallback.OnFallback != nil {
+			fallback.OnFallback(serviceName, resolved, defaultURL, err)
+		}
+
+		return defaultURL, nil
+	}
+
+	return resolved, nil
+}
+
+// resolves a URL of a service, optionally ignoring the branch property
+func (p Properties) resolve(serviceName string, defaultBranch bool) (*url.URL, error) {
+	rawURL := p.Get(serviceName, "url", "")
+
+	if rawURL == "" {
+		endpoint, ok := registeredEndpoint(serviceName)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownEndpoint, serviceName)
+		}
+
+		rawURL = endpoint.resolve(p, serviceName, defaultBranch)
+	}
+
+	if strings.Contains(rawURL, UrlVersionPlaceholder) {
+		return nil, fmt.Errorf("%w: no version of service %q for %q", ErrInvalidEndpoint, serviceName, rawURL)
+	}
+
+	if strings.Contains(rawURL, UrlBranchPlaceholder) {
+		return nil, fmt.Errorf("%w: no valid branch of service %q for %q", ErrInvalidEndpoint, serviceName, rawURL)
+	}
+
+	resolved, err := url.Parse(rawURL)
+	if err != nil || resolved.Scheme == "" || resolved.Host == "" {
+		return nil, fmt.Errorf("%w: %q of service %q", ErrInvalidEndpoint, rawURL, serviceName)
+	}
+
+	if port := p.Get(serviceName, "port", ""); port != "" {
+		if number, err := strconv.Atoi(port); err != nil || number <= 0 || number > 65535 {
+			return nil, fmt.Errorf("%w: port %q of service %q", ErrInvalidEndpoint, port, serviceName)
+		}
+
+		resolved.Host = net.JoinHostPort(resolved.Hostname(), port)
+	}
+
+	return resolved, nil
+}
+
+// replaces the placeholders in the endpoint URL
+func (e Endpoint) resolve(props Properties, serviceName string, defaultBranch bool) string {
+	branch := e.Branch
+	if !defaultBranch {
+		branch = props.Get(serviceName, "branch", e.Branch)
+	}
+
+	if branch == "" {
+		branch = currentDefaultBranch()
+	}
+
+	// an invalid branch leaves the placeholder in place, making the URL invalid
+	result := e.URL
+	if branch, ok := normalizeBranch(branch); ok {
+		result = strings.ReplaceAll(result, UrlBranchPlaceholder, branch)
+	}
+
+	if version := props.Get(serviceName, "version", e.Version); version != "" {
+		result = strings.ReplaceAll(result, UrlVersionPlaceholder, url.PathEscape(version))
+	}
+
+	return result
+}
@@ -0,0 +1,109 @@
+package servicectx
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// removes the endpoints registered with Register
+func resetEndpoints() {
+	endpoints.Lock()
+	defer endpoints.Unlock()
+
+	endpoints.byService = map[string]Endpoint{}
+}
+
+func TestResolve(t *testing.T) {
+	defer resetEndpoints()
+	Register("billing", "http://billing-$branch:8080/api")
+	RegisterEndpoint("user-profile", Endpoint{URL: "http://profile-$branch/$version", Branch: "master", Version: "v1"})
+
+	tests := []struct {
+		name    string
+		service string
+		props   Properties
+		want    string
+		wantErr error
+	}{
+		{name: "default", service: "billing", props: New(), want: "http://billing-main:8080/api"},
+		{name: "endpoint defaults", service: "user-profile", props: New(), want: "http://profile-master/v1"},
+		{
+			name:    "branch and version",
+			service: "user-profile",
+			props:   New().Set("user-profile", "branch", "Feature_123").Set("user-profile", "version", "v2"),
+			want:    "http://profile-feature-123/v2",
+		},
+		{
+			name:    "url has a priority over branch",
+			service: "billing",
+			props:   New().Set("billing", "url", "https://localhost:9000/v2").Set("billing", "branch", "feature-123"),
+			want:    "https://localhost:9000/v2",
+		},
+		{
+			name:    "port",
+			service: "billing",
+			props:   New().Set("billing", "branch", "feature-123").Set("billing", "port", "9090"),
+			want:    "http://billing-feature-123:9090/api",
+		},
+		{
+			name:    "port overrides url",
+			service: "billing",
+			props:   New().Set("billing", "url", "http://localhost/api").Set("billing", "port", "9090"),
+			want:    "http://localhost:9090/api",
+		},
+		{
+			name:    "url of unregistered service",
+			service: "api",
+			props:   New().Set("api", "url", "http://api"),
+			want:    "http://api",
+		},
+		{name: "unknown service", service: "api", props: New(), wantErr: ErrUnknownEndpoint},
+		{name: "invalid url", service: "billing", props: New().Set("billing", "url", "billing"), wantErr: ErrInvalidEndpoint},
+		{name: "invalid port", service: "billing", props: New().Set("billing", "port", "http"), wantErr: ErrInvalidEndpoint},
+		{name: "invalid branch", service: "billing", props: New().Set("billing", "branch", "evil.com/x"), wantErr: ErrInvalidEndpoint},
+		{
+			name:    "escaped version",
+			service: "user-profile",
+			props:   New().Set("user-profile", "version", "../admin?x=1"),
+			want:    "http://profile-master/..%2Fadmin%3Fx=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.props.InjectIntoContext(context.Background()), tt.service)
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestResolve_MissingVersion(t *testing.T) {
+	defer resetEndpoints()
+	Register("billing", "http://billing/$version")
+
+	_, err := New().Resolve("billing")
+	require.True(t, errors.Is(err, ErrInvalidEndpoint))
+
+	got, err := New().Set("billing", "version", "v3").Resolve("billing")
+	require.NoError(t, err)
+	require.Equal(t, "http://billing/v3", got.String())
+}
+
+func TestSetDefaultBranch(t *testing.T) {
+	defer resetEndpoints()
+	defer SetDefaultBranch("main")
+	Register("billing", "http://billing-$branch:8080")
+
+	SetDefaultBranch("master")
+	got, err := New().Resolve("billing")
+	require.NoError(t, err)
+	require.Equal(t, "http://billing-master:8080", got.String())
+}
@@ -0,0 +1,175 @@
+package servicectx
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Property metadata is kept apart from the properties, and isn't visible through Get, Range, Equal and other methods.
+// It is propagated next to the property itself, as an entry named "{PROPERTY}.{ATTRIBUTE}", e.g. "x-service-api-url.hops: 2".
+// This way it survives any carrier, and needs no support from older services.
+// Because of that, the names ending with ".hops", ".maxhops", ".expires", ".consume", and ".source" are reserved:
+// the properties with such names are not propagated.
+const (
+	// HopsAttribute is a metadata attribute holding the number of hops a property can still travel (see SetHops)
+	HopsAttribute = "hops"
+	// MaxHopsAttribute is a metadata attribute holding the number of hops a property was given with SetHops.
+	// Unlike the hop counter, it never changes, so it is signed (see Signing), and the counter is capped by it.
+	MaxHopsAttribute = "maxhops"
+	// ExpiresAttribute is a metadata attribute holding the time when a property expires (see SetExpiry)
+	ExpiresAttribute = "expires"
+)
+
+const metaSeparator = "."
+
+var metaAttributes = map[string]bool{
+	HopsAttribute:    true,
+	MaxHopsAttribute: true,
+	ExpiresAttribute: true,
+	ConsumeAttribute: true,
+	SourceAttribute:  true,
+}
+
+// metadata attributes passed to downstream services, in the order of propagation
+var propagatedAttributes = []string{ConsumeAttribute, ExpiresAttribute, HopsAttribute, MaxHopsAttribute}
+
+// SetHops limits the number of services a property can be received by, e.g. 1 means only the next service.
+// Each service receiving the property decrements the counter, and it is not passed further once the counter reaches zero.
+//
+// The counter can't be raised above the given number of hops or removed on the way, as that number is propagated
+// and signed along with the property. However, the counter itself can't be signed, as every service changes it,
+// so a tampering service on the way can reset it to the given number, making the property travel further.
+// Use SetExpiry along with Signing.MaxAge to bound the lifetime of a property regardless of such tampering.
+func (p Properties) SetHops(serviceName, prop string, hops int) Properties {
+	serviceName = p.serviceKey(serviceName)
+	p.setHidden(HopsAttribute, serviceName, prop, strconv.Itoa(hops))
+	p.setHidden(MaxHopsAttribute, serviceName, prop, strconv.Itoa(hops))
+
+	return p
+}
+
+// Hops returns the number of hops a property can still travel, and whether it's limited at all
+func (p Properties) Hops(serviceName, prop string) (int, bool) {
+	return p.hopsLeft(p.resolveService(serviceName, prop), prop)
+}
+
+// returns the number of hops a property of a service with an exact name can still travel, and whether it's limited:
+// its hop counter capped by its maximum hops, or the maximum hops if the counter is missing
+func (p Properties) hopsLeft(serviceName, prop string) (int, bool) {
+	hops, hasHops := p.intMeta(serviceName, prop, HopsAttribute)
+	maxHops, hasMaxHops := p.intMeta(serviceName, prop, MaxHopsAttribute)
+
+	if hasMaxHops && (!hasHops || hops > maxHops) {
+		return maxHops, true
+	}
+
+	return hops, hasHops
+}
+
+// returns an integer metadata attribute of a property of a service with an exact name, if it's valid
+func (p Properties) intMeta(serviceName, prop, attribute string) (int, bool) {
+	if value, ok := p.meta(serviceName, prop, attribute); ok {
+		if number, err := strconv.Atoi(value); err == nil {
+			return number, true
+		}
+	}
+
+	return 0, false
+}
+
+// SetExpiry sets a time when a property expires. Expired properties are dropped when parsed or injected.
+func (p Properties) SetExpiry(serviceName, prop string, expires time.Time) Properties {
+	p.setHidden(ExpiresAttribute, p.serviceKey(serviceName), prop, expires.UTC().Format(time.RFC3339))
+
+	return p
+}
+
+// Expiry returns a time when a property expires, and whether it expires at all
+func (p Properties) Expiry(serviceName, prop string) (time.Time, bool) {
+	if value, ok := p.meta(p.resolveService(serviceName, prop), prop, ExpiresAttribute); ok {
+		if expires, err := time.Parse(time.RFC3339, value); err == nil {
+			return expires, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// returns a copy of properties without the expired ones and the ones with no hops left.
+// If the properties were just received, their hop counters are decremented.
+func (p Properties) withoutExpired(now time.Time, received bool) Properties {
+	result := New()
+
+	p.each(func(serviceName, prop string, values []string) {
+		if p.isExpired(serviceName, prop, now) {
+			return
+		}
+
+		result.setValues(serviceName, prop, values)
+		result.copyMetadata(p, serviceName, prop)
+
+		if hops, ok := p.hopsLeft(serviceName, prop); ok && received {
+			result.setHidden(HopsAttribute, serviceName, prop, strconv.Itoa(hops-1))
+		}
+	})
+
+	return result
+}
+
+// checks if a property of a service with an exact name is expired or has no hops left
+func (p Properties) isExpired(serviceName, prop string, now time.Time) bool {
+	if hops, ok := p.hopsLeft(serviceName, prop); ok && hops <= 0 {
+		return true
+	}
+
+	if expires, ok := p.meta(serviceName, prop, ExpiresAttribute); ok {
+		if expires, err := time.Parse(time.RFC3339, expires); err == nil && now.After(expires) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// returns a metadata attribute of a property of a service with an exact name, if the property exists
+func (p Properties) meta(serviceName, prop, attribute string) (string, bool) {
+	if _, ok := p.first(serviceName, prop); !ok {
+		return "", false
+	}
+
+	return p.hidden(attribute, serviceName, prop)
+}
+
+// replaces all metadata and sources of a property of a service with an exact name with the ones from other properties
+func (p Properties) copyMetadata(other Properties, serviceName, prop string) {
+	if sources, ok := other.sources[propertyKey{serviceName, prop}]; ok {
+		p.sources[propertyKey{serviceName, prop}] = append([]Source(nil), sources...)
+	} else {
+		delete(p.sources, propertyKey{serviceName, prop})
+	}
+
+	for attribute := range metaAttributes {
+		if value, ok := other.hidden(attribute, serviceName, prop); ok {
+			p.setHidden(attribute, serviceName, prop, value)
+		} else {
+			p.deleteHidden(attribute, serviceName, prop)
+		}
+	}
+}
+
+// returns a name of a metadata property, e.g. "url.hops"
+func metaName(prop, attribute string) string {
+	return prop + metaSeparator + attribute
+}
+
+// splits a metadata property name like "url.hops" into a property name and an attribute.
+// For regular properties, the property name is returned as is.
+func splitMetaName(name string) (prop, attribute string, isMeta bool) {
+	i := strings.LastIndex(name, metaSeparator)
+	if i < 0 || !metaAttributes[name[i+1:]] {
+		return name, "", false
+	}
+
+	return name[:i], name[i+1:], true
+}
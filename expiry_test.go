@@ -0,0 +1,159 @@
+package servicectx
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestProperties_SetHops(t *testing.T) {
+	props := New().Set("api", "url", "http://api").SetHops("api", "url", 2)
+
+	hops, ok := props.Hops("api", "url")
+	require.True(t, ok)
+	require.Equal(t, 2, hops)
+	require.False(t, props.HasProperty("api", "url.hops"), "metadata must not be visible as a property")
+	require.Equal(t, Values{"url": "http://api"}, props.GetByService("api"))
+	require.True(t, props.Equal(New().Set("api", "url", "http://api")), "metadata must not affect equality")
+
+	_, ok = props.Hops("api", "branch")
+	require.False(t, ok)
+}
+
+func TestProperties_SetExpiry(t *testing.T) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	props := New().Set("api", "url", "http://api").SetExpiry("api", "url", expires)
+
+	got, ok := props.Expiry("api", "url")
+	require.True(t, ok)
+	require.True(t, expires.Equal(got))
+	require.False(t, props.HasProperty("api", "url.expires"), "metadata must not be visible as a property")
+	require.Equal(
+		t,
+		map[string]string{"x-service-api-url": "http://api", "x-service-api-url.expires": "2030-01-02T03:04:05Z"},
+		props.HeaderMap(),
+	)
+
+	_, ok = props.Expiry("api", "branch")
+	require.False(t, ok)
+}
+
+func TestProperties_ReservedNames(t *testing.T) {
+	props := New().Set("api", "url", "http://api").Set("api", "url.hops", "0")
+
+	// a property with a reserved name is a regular property, not the metadata of another one
+	_, ok := props.Hops("api", "url")
+	require.False(t, ok)
+	require.Equal(t, "0", props.Get("api", "url.hops", ""))
+
+	// but it's not propagated, so that it isn't reinterpreted by a receiver
+	require.Equal(t, map[string][]string{"x-service-api-url": {"http://api"}}, props.Entries())
+
+	// metadata is hidden from every method listing properties
+	props = FromQueryString("x-service-api-url=http://api&x-service-api-url.hops=2&x-service-api-url.consume=true")
+	require.Equal(t, []string{"api"}, props.Services())
+	require.Equal(t, Values{"url": "http://api"}, props.GetByService("api"))
+	require.True(t, props.Diff(New().Set("api", "url", "http://api")).IsEmpty())
+	props.Range(func(serviceName, prop string, values []string) bool {
+		require.Equal(t, "url", prop)
+		return true
+	})
+	require.Equal(t, []string{"api"}, props.Filter(func(serviceName, prop string, values []string) bool { return true }).Services())
+
+	hops, ok := props.Filter(func(serviceName, prop string, values []string) bool { return true }).Hops("api", "url")
+	require.True(t, ok, "metadata must be kept along with a property")
+	require.Equal(t, 1, hops)
+}
+
+func TestFromHeaders_Hops(t *testing.T) {
+	headers := http.Header{}
+	New().
+		Set("api", "url", "http://api").
+		SetHops("api", "url", 2).
+		Set("api", "branch", "main").
+		InjectIntoHeaders(headers)
+
+	// first service
+	props := FromHeaders(headers)
+	require.Equal(t, "http://api", props.Get("api", "url", ""))
+	hops, _ := props.Hops("api", "url")
+	require.Equal(t, 1, hops)
+
+	// second service
+	headers = http.Header{}
+	props.InjectIntoHeaders(headers)
+	props = FromHeaders(headers)
+	require.Equal(t, "http://api", props.Get("api", "url", ""))
+	hops, _ = props.Hops("api", "url")
+	require.Equal(t, 0, hops)
+
+	// no hops left, so the property is not passed further
+	headers = http.Header{}
+	props.InjectIntoHeaders(headers)
+	require.Equal(t, http.Header{"X-Service-Api-Branch": {"main"}}, headers)
+}
+
+func TestFromHeaders_MaxHops(t *testing.T) {
+	headers := http.Header{}
+	New().Set("api", "url", "http://api").SetHops("api", "url", 2).InjectIntoHeaders(headers)
+	require.Equal(t, []string{"2"}, headers.Values("x-service-api-url.maxhops"))
+
+	headers.Set("x-service-api-url.hops", "100")
+	hops, _ := FromHeaders(headers).Hops("api", "url")
+	require.Equal(t, 1, hops, "the hop counter must be capped by the maximum hops")
+
+	headers.Del("x-service-api-url.hops")
+	hops, _ = FromHeaders(headers).Hops("api", "url")
+	require.Equal(t, 1, hops, "a removed hop counter must be restored from the maximum hops")
+}
+
+func TestFromHeaders_NoHopsLeft(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("x-service-api-url", "http://api")
+	headers.Set("x-service-api-url.hops", "0")
+	headers.Set("x-service-api-branch", "main")
+
+	require.Equal(t, New().Set("api", "branch", "main"), FromHeaders(headers))
+}
+
+func TestFromHeaders_Expiry(t *testing.T) {
+	headers := http.Header{}
+	New().
+		Set("api", "url", "http://api").
+		SetExpiry("api", "url", time.Now().Add(time.Hour)).
+		Set("api", "branch", "main").
+		SetExpiry("api", "branch", time.Now().Add(-time.Hour)).
+		InjectIntoHeaders(headers)
+
+	require.Equal(t, []string{"http://api"}, headers.Values("x-service-api-url"))
+	require.Empty(t, headers.Values("x-service-api-branch"))
+	require.Empty(t, headers.Values("x-service-api-branch.expires"))
+
+	headers.Set("x-service-api-url.expires", time.Now().Add(-time.Minute).UTC().Format(time.RFC3339))
+	require.Equal(t, New(), FromHeaders(headers))
+}
+
+func TestFromHeaders_OrphanMetadata(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("x-service-api-url.hops", "2")
+
+	require.Equal(t, New(), FromHeaders(headers))
+}
+
+func TestFromQueryString_Hops(t *testing.T) {
+	props := FromQueryString("x-service-api-url=http://api&x-service-api-url.hops=1")
+	require.Equal(t, "http://api", props.Get("api", "url", ""))
+	require.Equal(t, "", props.QueryString())
+}
+
+func TestPolicy_AllowsMetadata(t *testing.T) {
+	SetPolicy(&Policy{Allow: []Rule{{Service: "api", Property: "url"}}})
+	defer SetPolicy(nil)
+
+	props := FromQueryString("x-service-api-url=http://api&x-service-api-url.hops=2&x-service-api-branch=main")
+
+	require.True(t, props.Equal(New().Set("api", "url", "http://api")))
+	hops, _ := props.Hops("api", "url")
+	require.Equal(t, 1, hops)
+}
@@ -0,0 +1,219 @@
+package servicectx
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ProbeFunc checks if a service is reachable at a given URL
+type ProbeFunc func(ctx context.Context, u *url.URL) error
+
+// Fallback makes Resolve check if an overridden branch is reachable, and fall back to the default branch otherwise,
+// so that a branch with no deployment doesn't break every call
+type Fallback struct {
+	// Probe checks if a branch URL is reachable; DialHost is used if nil
+	Probe ProbeFunc
+	// Timeout limits each probe; 1 second is used if zero
+	Timeout time.Duration
+	// TTL is how long probe results are cached; zero means they are not cached
+	TTL time.Duration
+	// MaxEntries limits the number of cached probe results; 1024 is used if zero.
+	// The expired results are evicted first, and then the ones expiring sooner.
+	MaxEntries int
+	// OnFallback, if set, is called whenever the default URL is used instead of an unreachable one, e.g. for logging
+	OnFallback func(serviceName string, unreachable, fallback *url.URL, err error)
+
+	mu      sync.Mutex
+	results map[string]probeResult
+	// the probes in progress, shared by concurrent checks of the same URL
+	calls map[string]*probeCall
+}
+
+// a cached result of a probe
+type probeResult struct {
+	err     error
+	expires time.Time
+}
+
+// a probe in progress
+type probeCall struct {
+	done chan struct{}
+	err  error
+}
+
+// the number of cached probe results when Fallback.MaxEntries is zero
+const defaultMaxProbeResults = 1024
+
+// the fallback set with SetFallback
+var fallback struct {
+	sync.RWMutex
+	value *Fallback
+}
+
+// SetFallback enables probing of overridden branches in Resolve. A nil value (the default) disables it.
+func SetFallback(f *Fallback) {
+	fallback.Lock()
+	defer fallback.Unlock()
+
+	fallback.value = f
+}
+
+// returns the fallback set with SetFallback
+func currentFallback() *Fallback {
+	fallback.RLock()
+	defer fallback.RUnlock()
+
+	return fallback.value
+}
+
+// LookupHost is a probe checking that the host of a URL can be resolved with DNS
+func LookupHost(ctx context.Context, u *url.URL) error {
+	_, err := net.DefaultResolver.LookupHost(ctx, u.Hostname())
+
+	return err
+}
+
+// DialHost is a probe checking that a TCP connection to the host and the port of a URL can be established
+func DialHost(ctx context.Context, u *url.URL) error {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// CheckHealth returns a probe requesting a health check path of a URL, e.g. "/health", and expecting a 2xx status
+func CheckHealth(path string) ProbeFunc {
+	return func(ctx context.Context, u *url.URL) error {
+		healthURL := *u
+		healthURL.Path = path
+		healthURL.RawPath = ""
+		healthURL.RawQuery = ""
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL.String(), nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("servicectx: health check of %s failed with status %d", healthURL.String(), resp.StatusCode)
+		}
+
+		return nil
+	}
+}
+
+// probes a URL, or returns a cached result. Concurrent checks of the same URL share a single probe,
+// which isn't bound to any of their contexts, so a check returns the error of its context when it's done first,
+// and the probe keeps running for the others.
+func (f *Fallback) check(ctx context.Context, u *url.URL) error {
+	key := u.Scheme + "://" + u.Host
+
+	f.mu.Lock()
+	if result, ok := f.results[key]; ok && time.Now().Before(result.expires) {
+		f.mu.Unlock()
+		return result.err
+	}
+
+	call, ok := f.calls[key]
+	if !ok {
+		call = &probeCall{done: make(chan struct{})}
+		if f.calls == nil {
+			f.calls = map[string]*probeCall{}
+		}
+		f.calls[key] = call
+		go f.run(key, u, call)
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runs a probe shared by concurrent checks, and caches its result
+func (f *Fallback) run(key string, u *url.URL, call *probeCall) {
+	call.err = f.probe(u)
+
+	f.mu.Lock()
+	delete(f.calls, key)
+	if f.TTL > 0 {
+		f.store(key, probeResult{err: call.err, expires: time.Now().Add(f.TTL)})
+	}
+	f.mu.Unlock()
+	close(call.done)
+}
+
+// caches a probe result, evicting the expired results, and then the ones expiring sooner, to stay within MaxEntries.
+// It must be called with the mutex locked.
+func (f *Fallback) store(key string, result probeResult) {
+	maxEntries := f.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxProbeResults
+	}
+
+	if f.results == nil {
+		f.results = map[string]probeResult{}
+	}
+
+	if _, ok := f.results[key]; !ok && len(f.results) >= maxEntries {
+		now := time.Now()
+		for cached, result := range f.results {
+			if !now.Before(result.expires) {
+				delete(f.results, cached)
+			}
+		}
+
+		for len(f.results) >= maxEntries {
+			var oldest string
+			for cached, result := range f.results {
+				if oldest == "" || result.expires.Before(f.results[oldest].expires) {
+					oldest = cached
+				}
+			}
+			delete(f.results, oldest)
+		}
+	}
+
+	f.results[key] = result
+}
+
+// probes a URL with a timeout, independently of the context of any request
+func (f *Fallback) probe(u *url.URL) error {
+	timeout := f.Timeout
+	if timeout == 0 {
+		timeout = time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if f.Probe != nil {
+		return f.Probe(ctx, u)
+	}
+
+	return DialHost(ctx, u)
+}
@@ -0,0 +1,232 @@
+package servicectx
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResolve_Fallback(t *testing.T) {
+	defer resetEndpoints()
+	Register("billing", "http://billing-$branch:8080")
+
+	var probed []string
+	var fallbacks []string
+	SetFallback(&Fallback{
+		Probe: func(ctx context.Context, u *url.URL) error {
+			probed = append(probed, u.String())
+			if u.Hostname() == "billing-missing" {
+				return errors.New("no such host")
+			}
+
+			return nil
+		},
+		TTL: time.Minute,
+		OnFallback: func(serviceName string, unreachable, fallback *url.URL, err error) {
+			fallbacks = append(fallbacks, serviceName+": "+unreachable.String()+" -> "+fallback.String()+": "+err.Error())
+		},
+	})
+	defer SetFallback(nil)
+
+	resolve := func(props Properties) string {
+		resolved, err := props.Resolve("billing")
+		require.NoError(t, err)
+
+		return resolved.String()
+	}
+
+	// nothing to probe without a branch override
+	require.Equal(t, "http://billing-main:8080", resolve(New()))
+	require.Equal(t, "http://localhost", resolve(New().Set("billing", "url", "http://localhost").Set("billing", "branch", "missing")))
+	require.Empty(t, probed)
+
+	require.Equal(t, "http://billing-feature:8080", resolve(New().Set("billing", "branch", "feature")))
+	require.Equal(t, "http://billing-main:8080", resolve(New().Set("billing", "branch", "missing")))
+	require.Equal(t, []string{"billing: http://billing-missing:8080 -> http://billing-main:8080: no such host"}, fallbacks)
+
+	// the results are cached
+	require.Equal(t, "http://billing-main:8080", resolve(New().Set("billing", "branch", "missing")))
+	require.Equal(t, []string{"http://billing-feature:8080", "http://billing-missing:8080"}, probed)
+	require.Len(t, fallbacks, 2)
+}
+
+func TestResolve_FallbackInvalidBranch(t *testing.T) {
+	defer resetEndpoints()
+	Register("billing", "http://billing-$branch:8080")
+
+	probed := 0
+	SetFallback(&Fallback{Probe: func(ctx context.Context, u *url.URL) error {
+		probed++
+		return nil
+	}})
+	defer SetFallback(nil)
+
+	_, err := New().Set("billing", "branch", "evil.com/x").Resolve("billing")
+	require.True(t, errors.Is(err, ErrInvalidEndpoint))
+	require.Zero(t, probed, "an invalid branch must not be probed")
+}
+
+func TestFallback_MaxEntries(t *testing.T) {
+	f := &Fallback{
+		Probe:      func(ctx context.Context, u *url.URL) error { return nil },
+		TTL:        time.Minute,
+		MaxEntries: 2,
+	}
+
+	for _, host := range []string{"a", "b", "c"} {
+		require.NoError(t, f.check(context.Background(), &url.URL{Scheme: "http", Host: host}))
+	}
+
+	require.Len(t, f.results, 2)
+	require.NotContains(t, f.results, "http://a", "the result expiring first must be evicted")
+
+	// the expired results are evicted first
+	f.results["http://b"] = probeResult{expires: time.Now().Add(-time.Second)}
+	require.NoError(t, f.check(context.Background(), &url.URL{Scheme: "http", Host: "d"}))
+	require.Len(t, f.results, 2)
+	require.Contains(t, f.results, "http://c")
+	require.Contains(t, f.results, "http://d")
+}
+
+func TestFallback_ConcurrentProbes(t *testing.T) {
+	var mu sync.Mutex
+	probes := 0
+	release := make(chan struct{})
+	f := &Fallback{
+		Probe: func(ctx context.Context, u *url.URL) error {
+			mu.Lock()
+			probes++
+			mu.Unlock()
+			<-release
+
+			return errors.New("unreachable")
+		},
+		// a check starting after the probe is done gets a cached result
+		TTL: time.Minute,
+	}
+
+	const concurrency = 10
+	errs := make([]error, concurrency)
+	var started, wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		started.Add(1)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			errs[i] = f.check(context.Background(), &url.URL{Scheme: "http", Host: "billing-missing"})
+		}(i)
+	}
+
+	started.Wait()
+	// let all checks reach the probe in progress
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, 1, probes, "concurrent checks of the same URL must share a probe")
+	for _, err := range errs {
+		require.EqualError(t, err, "unreachable")
+	}
+}
+
+func TestFallback_CanceledCheck(t *testing.T) {
+	probes := make(chan context.Context, 2)
+	release := make(chan struct{})
+	f := &Fallback{
+		Probe: func(ctx context.Context, u *url.URL) error {
+			probes <- ctx
+			<-release
+
+			return nil
+		},
+		TTL: time.Minute,
+	}
+	u := &url.URL{Scheme: "http", Host: "billing-feature"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		errs <- f.check(ctx, u)
+	}()
+
+	probeCtx := <-probes
+	cancel()
+	require.ErrorIs(t, <-errs, context.Canceled)
+	require.NoError(t, probeCtx.Err(), "the probe must not be canceled with the request starting it")
+
+	close(release)
+	require.NoError(t, f.check(context.Background(), u))
+	require.Empty(t, probes, "the result of the probe must be cached")
+}
+
+func TestResolve_FallbackCanceled(t *testing.T) {
+	defer resetEndpoints()
+	Register("billing", "http://billing-$branch:8080")
+
+	var fallbacks int
+	SetFallback(&Fallback{
+		Probe: func(ctx context.Context, u *url.URL) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		Timeout: 50 * time.Millisecond,
+		OnFallback: func(serviceName string, unreachable, fallback *url.URL, err error) {
+			fallbacks++
+		},
+	})
+	defer SetFallback(nil)
+
+	ctx, cancel := context.WithCancel(WithProperties(context.Background(), New().Set("billing", "branch", "feature")))
+	cancel()
+	_, err := Resolve(ctx, "billing")
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, fallbacks)
+
+	// a probe timing out means the branch is unreachable
+	resolved, err := Resolve(WithProperties(context.Background(), New().Set("billing", "branch", "feature")), "billing")
+	require.NoError(t, err)
+	require.Equal(t, "http://billing-main:8080", resolved.String())
+	require.Equal(t, 1, fallbacks)
+}
+
+func TestResolve_FallbackDisabled(t *testing.T) {
+	defer resetEndpoints()
+	Register("billing", "http://billing-$branch:8080")
+
+	resolved, err := Resolve(New().Set("billing", "branch", "missing").InjectIntoContext(context.Background()), "billing")
+	require.NoError(t, err)
+	require.Equal(t, "http://billing-missing:8080", resolved.String())
+}
+
+func TestDialHost(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+
+	require.NoError(t, DialHost(context.Background(), &url.URL{Scheme: "http", Host: address}))
+
+	require.NoError(t, listener.Close())
+	require.Error(t, DialHost(context.Background(), &url.URL{Scheme: "http", Host: address}))
+}
+
+func TestCheckHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/api?page=1")
+
+	require.NoError(t, CheckHealth("/health")(context.Background(), u))
+	require.Error(t, CheckHealth("/ready")(context.Background(), u))
+}
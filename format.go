@@ -0,0 +1,190 @@
+package servicectx
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Format describes how property names are composed of a prefix, a service name, and an option name,
+// e.g. `x-service-{SERVICE_NAME}-{OPTION_NAME}`.
+type Format struct {
+	// Prefix at the beginning of a property name indicating it belongs to this package
+	Prefix string
+	// Separator between the prefix, the service name, and the option name
+	Separator string
+	// CaseSensitive disables lowercasing of property names when they are parsed.
+	// HTTP headers are case-insensitive, so it should only be enabled for case-preserving carriers.
+	CaseSensitive bool
+	// Duplicates defines which values of a repeated property are kept when parsing
+	Duplicates DuplicatePolicy
+}
+
+// DuplicatePolicy defines which values of a repeated property (e.g. a repeated HTTP header) are kept when parsing
+type DuplicatePolicy int
+
+const (
+	// KeepAll keeps all values: Get returns the first one, and GetAll returns all of them
+	KeepAll DuplicatePolicy = iota
+	// KeepFirst keeps only the first value
+	KeepFirst
+	// KeepLast keeps only the last value
+	KeepLast
+)
+
+// DefaultFormat is the standard `x-service-{SERVICE_NAME}-{OPTION_NAME}` format.
+// It is used by all functions that don't accept a format explicitly.
+var DefaultFormat = Format{
+	Prefix:    NamePrefix,
+	Separator: Separator,
+}
+
+// ParsePropertyName parses a string like "x-service-api-branch" into service name ("api"),
+// property name ("branch"), and a boolean success flag.
+//
+// A separator inside a service name is escaped by doubling it: "x-service-user--profile-branch"
+// is parsed as service "user-profile". The names declared with RegisterServiceName are recognized without escaping.
+func (f Format) ParsePropertyName(name string) (serviceName, option string, ok bool) {
+	prefix := f.Prefix + f.Separator
+	if !f.CaseSensitive {
+		name = strings.ToLower(name)
+		prefix = strings.ToLower(prefix)
+	}
+
+	if !strings.HasPrefix(name, prefix) {
+		return "", "", false
+	}
+
+	name = strings.TrimPrefix(name, prefix)
+
+	serviceName, option = f.splitRegisteredServiceName(name)
+	if serviceName == "" {
+		serviceName, option = f.splitEscapedServiceName(name)
+	}
+
+	if serviceName == "" || option == "" {
+		return "", "", false
+	}
+
+	return serviceName, option, true
+}
+
+// GetPropertyName builds a string from service name and property name
+func (f Format) GetPropertyName(serviceName, option string) string {
+	serviceName = strings.ReplaceAll(serviceName, f.Separator, f.Separator+f.Separator)
+
+	return f.Prefix + f.Separator + serviceName + f.Separator + option
+}
+
+// SignatureName returns a name of the property set signature, e.g. "x-service-signature" (see SetSigning)
+func (f Format) SignatureName() string {
+	return f.Prefix + f.Separator + signatureName
+}
+
+const signatureName = "signature"
+
+// JoinValues encodes multiple values of a property into a single string,
+// for carriers that don't support repeated keys, such as OpenTelemetry and OpenTracing baggage.
+// The values are separated by "|". The separator, percent signs, and characters not allowed in W3C baggage values
+// (such as commas and spaces) are percent-encoded, so a simple value like "feature-123" is kept as is.
+func JoinValues(values []string) string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = escapeValue(value)
+	}
+
+	return strings.Join(escaped, valueListSeparator)
+}
+
+// SplitValues decodes multiple values of a property encoded with JoinValues
+func SplitValues(value string) []string {
+	values := strings.Split(value, valueListSeparator)
+	for i := range values {
+		if unescaped, err := url.PathUnescape(values[i]); err == nil {
+			values[i] = unescaped
+		}
+	}
+
+	return values
+}
+
+const valueListSeparator = "|"
+
+// percent-encodes everything except for W3C baggage-octets, with an exception of "%" and "|"
+func escapeValue(value string) string {
+	var result strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if isBaggageOctet(c) && c != '%' && c != '|' {
+			result.WriteByte(c)
+		} else {
+			fmt.Fprintf(&result, "%%%02X", c)
+		}
+	}
+
+	return result.String()
+}
+
+// checks if a character is allowed in W3C baggage value
+func isBaggageOctet(c byte) bool {
+	return c == 0x21 ||
+		(c >= 0x23 && c <= 0x2b) ||
+		(c >= 0x2d && c <= 0x3a) ||
+		(c >= 0x3c && c <= 0x5b) ||
+		(c >= 0x5d && c <= 0x7e)
+}
+
+// returns the values to be kept according to the policy
+func (d DuplicatePolicy) apply(values []string) []string {
+	if len(values) < 2 {
+		return values
+	}
+
+	switch d {
+	case KeepFirst:
+		return values[:1]
+	case KeepLast:
+		return values[len(values)-1:]
+	default:
+		return values
+	}
+}
+
+// splits "user-profile-branch" into "user-profile" and "branch", if "user-profile" is a registered service name
+func (f Format) splitRegisteredServiceName(name string) (serviceName, option string) {
+	for _, registered := range registeredServiceNames() {
+		if !f.CaseSensitive {
+			registered = strings.ToLower(registered)
+		}
+
+		if strings.HasPrefix(name, registered+f.Separator) {
+			return registered, strings.TrimPrefix(name, registered+f.Separator)
+		}
+	}
+
+	return "", ""
+}
+
+// splits "user--profile-branch" into "user-profile" and "branch" at the first non-doubled separator
+func (f Format) splitEscapedServiceName(name string) (serviceName, option string) {
+	if f.Separator == "" {
+		return "", ""
+	}
+
+	escapedSeparator := f.Separator + f.Separator
+
+	for i := 0; i < len(name); {
+		switch {
+		case strings.HasPrefix(name[i:], escapedSeparator):
+			i += len(escapedSeparator)
+		case strings.HasPrefix(name[i:], f.Separator):
+			serviceName = strings.ReplaceAll(name[:i], escapedSeparator, f.Separator)
+			return serviceName, name[i+len(f.Separator):]
+		default:
+			i++
+		}
+	}
+
+	return "", ""
+}
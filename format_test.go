@@ -0,0 +1,111 @@
+package servicectx
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestFormat_ParsePropertyName(t *testing.T) {
+	format := Format{Prefix: "x-kt", Separator: "-"}
+
+	tests := []struct {
+		name            string
+		property        string
+		wantServiceName string
+		wantProperty    string
+		wantOk          bool
+	}{
+		{
+			name:     "default prefix",
+			property: "x-service-api-branch",
+			wantOk:   false,
+		},
+		{
+			name:     "prefix with no separator",
+			property: "x-ktapi-branch",
+			wantOk:   false,
+		},
+		{
+			name:     "empty service name",
+			property: "x-kt--branch",
+			wantOk:   false,
+		},
+		{
+			name:            "custom prefix",
+			property:        "x-kt-api-branch",
+			wantServiceName: "api",
+			wantProperty:    "branch",
+			wantOk:          true,
+		},
+		{
+			name:            "custom prefix in upper case",
+			property:        "X-Kt-Api-Timeout-Milliseconds",
+			wantServiceName: "api",
+			wantProperty:    "timeout-milliseconds",
+			wantOk:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotServiceName, gotOption, gotOk := format.ParsePropertyName(tt.property)
+			if !tt.wantOk {
+				require.False(t, gotOk)
+				return
+			}
+
+			require.True(t, gotOk)
+			require.Equal(t, tt.wantServiceName, gotServiceName)
+			require.Equal(t, tt.wantProperty, gotOption)
+		})
+	}
+}
+
+func TestFormat_CaseSensitive(t *testing.T) {
+	format := Format{Prefix: "kt", Separator: "_", CaseSensitive: true}
+
+	require.Equal(t, "kt_api_logLevel", format.GetPropertyName("api", "logLevel"))
+
+	serviceName, option, ok := format.ParsePropertyName("kt_api_logLevel")
+	require.True(t, ok)
+	require.Equal(t, "api", serviceName)
+	require.Equal(t, "logLevel", option)
+
+	_, _, ok = format.ParsePropertyName("KT_api_logLevel")
+	require.False(t, ok, "a prefix must match exactly in case-sensitive format")
+}
+
+func TestFormat_Carriers(t *testing.T) {
+	format := Format{Prefix: "x-kt", Separator: "-"}
+	props := New().Set("api", "branch", "feature-123")
+
+	require.Equal(t, map[string]string{"x-kt-api-branch": "feature-123"}, props.HeaderMapWithFormat(format))
+	require.Equal(t, "x-kt-api-branch=feature-123", props.QueryStringWithFormat(format))
+
+	headers := http.Header{}
+	props.InjectIntoHeadersWithFormat(headers, format)
+	require.Equal(t, "feature-123", headers.Get("X-Kt-Api-Branch"))
+
+	require.Equal(t, props, FromHeadersWithFormat(headers, format))
+	require.Empty(t, FromHeaders(headers).Services(), "properties of a custom format must be ignored by default")
+
+	require.Equal(t, props, FromQueryStringWithFormat("x-kt-api-branch=feature-123&x-service-api-branch=main", format))
+
+	req, _ := http.NewRequest("GET", "/?x-kt-api-version=2", nil)
+	props.InjectIntoHeadersWithFormat(req.Header, format)
+	require.Equal(
+		t,
+		New().Set("api", "branch", "feature-123").Set("api", "version", "2"),
+		FromRequestWithFormat(req, format),
+	)
+}
+
+func TestJoinValues(t *testing.T) {
+	require.Equal(t, "feature-123", JoinValues([]string{"feature-123"}))
+	require.Equal(t, "http://api", JoinValues([]string{"http://api"}))
+	require.Equal(t, "a|b%2Cc|100%25%7C%20", JoinValues([]string{"a", "b,c", "100%| "}))
+
+	require.Equal(t, []string{"a", "b,c", "100%| "}, SplitValues("a|b%2Cc|100%25%7C%20"))
+	require.Equal(t, []string{""}, SplitValues(""))
+	require.Equal(t, []string{"100%"}, SplitValues("100%"), "a malformed escape sequence must be kept as is")
+}
@@ -0,0 +1,342 @@
+package servicectx
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Limits restricts the amount of properties, so that headers or baggage can't be abused.
+// Zero values mean no limit.
+type Limits struct {
+	// MaxServices is the maximum number of services
+	MaxServices int
+	// MaxPropertiesPerService is the maximum number of properties of a single service
+	MaxPropertiesPerService int
+	// MaxProperties is the maximum total number of properties
+	MaxProperties int
+	// MaxKeyLength is the maximum length of a property name in a carrier, e.g. "x-service-api-branch"
+	MaxKeyLength int
+	// MaxValueLength is the maximum length of a property value
+	MaxValueLength int
+	// MaxEncodedSize is the maximum total length of property names and values in a carrier
+	MaxEncodedSize int
+	// Reject drops all properties if any limit is exceeded. Otherwise, only the properties exceeding the limits are dropped.
+	Reject bool
+	// Report, if set, is called whenever limits are exceeded when parsing or injecting properties
+	Report func(report *LimitReport)
+}
+
+// LimitViolation describes a property dropped because of a limit
+type LimitViolation struct {
+	Service  string
+	Property string
+	// Limit is the name of the exceeded limit, e.g. "MaxValueLength"
+	Limit string
+}
+
+func (v LimitViolation) String() string {
+	return fmt.Sprintf("property %q of service %q exceeds %s", v.Property, v.Service, v.Limit)
+}
+
+// LimitReport lists the properties dropped because of limits
+type LimitReport struct {
+	Violations []LimitViolation
+	// Rejected means that all properties were dropped because of the Reject option
+	Rejected bool
+}
+
+// Exceeded checks if any limit was exceeded
+func (r *LimitReport) Exceeded() bool {
+	return r != nil && len(r.Violations) > 0
+}
+
+// the limits set with SetLimits
+var limits struct {
+	sync.RWMutex
+	value *Limits
+}
+
+// SetLimits sets limits applied to properties parsed from all sources (like the policy set with SetPolicy),
+// as well as to properties injected into all carriers. A nil value (the default) means no limits.
+//
+// When parsing, the limits are enforced twice: first on the entries as received, before they are decrypted, verified and parsed,
+// so that oversized input is dropped as early as possible, and then on the parsed properties.
+// Both when parsing and injecting, the properties are counted the same way, as they are encoded in a carrier:
+// the lengths of encrypted values are checked as they are, and the metadata entries like "x-service-api-url.hops"
+// count towards MaxKeyLength, MaxValueLength and MaxEncodedSize, but not towards the number of properties.
+// A property is dropped along with its metadata, and the metadata not fitting the limits drops the property.
+func SetLimits(l *Limits) {
+	limits.Lock()
+	defer limits.Unlock()
+
+	limits.value = l
+}
+
+// returns the limits set with SetLimits
+func currentLimits() *Limits {
+	limits.RLock()
+	defer limits.RUnlock()
+
+	return limits.value
+}
+
+// Enforce returns a copy of properties within the limits, as they would be encoded with a given format,
+// along with a report of the dropped ones.
+// Services and properties are processed in alphabetical order, so the same ones are dropped every time.
+func (l *Limits) Enforce(props Properties, format Format) (Properties, *LimitReport) {
+	report := &LimitReport{}
+	if l == nil {
+		return props.Clone(), report
+	}
+
+	result := New()
+	counter := &limitCounter{limits: l, report: report}
+	sealing := currentSealing()
+
+	for _, serviceName := range props.Services() {
+		if !counter.startService(serviceName) {
+			continue
+		}
+
+		for _, prop := range sortedKeys(props.services[serviceName]) {
+			key := format.GetPropertyName(serviceName, prop)
+			propValues, _ := props.values(serviceName, prop)
+
+			values := make([]limitEntry, 0, len(propValues))
+			for _, value := range propValues {
+				value, _ := sealing.seal(serviceName, prop, value)
+				values = append(values, limitEntry{name: key, value: value})
+			}
+
+			var metadata []limitEntry
+			for _, attribute := range propagatedAttributes {
+				if value, ok := props.hidden(attribute, serviceName, prop); ok {
+					name := metaName(prop, attribute)
+					value, _ := sealing.seal(serviceName, name, value)
+					metadata = append(metadata, limitEntry{name: format.GetPropertyName(serviceName, name), value: value})
+				}
+			}
+
+			for _, i := range counter.property(serviceName, prop, values, metadata) {
+				result.addValue(serviceName, prop, propValues[i])
+			}
+
+			if result.HasProperty(serviceName, prop) {
+				result.copyMetadata(props, serviceName, prop)
+			}
+		}
+
+		counter.endService()
+	}
+
+	if l.Reject && report.Exceeded() {
+		report.Rejected = true
+		result = New()
+	}
+
+	return result, report
+}
+
+// enforces the limits on entries as they are received from a carrier, and calls the Report function if they were exceeded.
+// The entries are counted like Enforce counts properties; the ones not matching the format, such as the signature, are kept as is,
+// and the metadata entries of missing properties are dropped, as they would be when parsing.
+func (l *Limits) applyEntries(entries map[string][]string, format Format) map[string][]string {
+	if l == nil {
+		return entries
+	}
+
+	type property struct {
+		values   []limitEntry
+		metadata []limitEntry
+	}
+
+	result := map[string][]string{}
+	services := map[string]map[string]*property{}
+
+	for _, name := range sortedKeys(entries) {
+		serviceName, option, ok := format.ParsePropertyName(name)
+		if !ok {
+			result[name] = entries[name]
+			continue
+		}
+
+		prop, attribute, isMeta := splitMetaName(option)
+		if isMeta && attribute == SourceAttribute {
+			continue
+		}
+
+		if _, ok := services[serviceName]; !ok {
+			services[serviceName] = map[string]*property{}
+		}
+
+		if _, ok := services[serviceName][prop]; !ok {
+			services[serviceName][prop] = &property{}
+		}
+
+		for _, value := range entries[name] {
+			if isMeta {
+				services[serviceName][prop].metadata = append(services[serviceName][prop].metadata, limitEntry{name: name, value: value})
+			} else {
+				services[serviceName][prop].values = append(services[serviceName][prop].values, limitEntry{name: name, value: value})
+			}
+		}
+	}
+
+	report := &LimitReport{}
+	counter := &limitCounter{limits: l, report: report}
+
+	for _, serviceName := range sortedKeys(services) {
+		if !counter.startService(serviceName) {
+			continue
+		}
+
+		for _, prop := range sortedKeys(services[serviceName]) {
+			entries := services[serviceName][prop]
+			if len(entries.values) == 0 {
+				continue
+			}
+
+			kept := counter.property(serviceName, prop, entries.values, entries.metadata)
+			for _, i := range kept {
+				result[entries.values[i].name] = append(result[entries.values[i].name], entries.values[i].value)
+			}
+
+			if len(kept) > 0 {
+				for _, entry := range entries.metadata {
+					result[entry.name] = append(result[entry.name], entry.value)
+				}
+			}
+		}
+
+		counter.endService()
+	}
+
+	if l.Reject && report.Exceeded() {
+		report.Rejected = true
+		result = map[string][]string{}
+	}
+
+	if report.Exceeded() && l.Report != nil {
+		l.Report(report)
+	}
+
+	return result
+}
+
+// an entry of a property as it is encoded in a carrier: a property name and one of its values
+type limitEntry struct {
+	name  string
+	value string
+}
+
+// counts the entries of properties against the limits the same way when parsing and injecting,
+// processing the services and their properties one by one
+type limitCounter struct {
+	limits            *Limits
+	report            *LimitReport
+	services          int
+	serviceProperties int
+	properties        int
+	encodedSize       int
+}
+
+// checks if one more service fits the limits before its properties are processed
+func (c *limitCounter) startService(serviceName string) bool {
+	if c.limits.MaxServices > 0 && c.services >= c.limits.MaxServices {
+		c.report.add(serviceName, "", "MaxServices")
+		return false
+	}
+
+	c.serviceProperties = 0
+
+	return true
+}
+
+// counts a service once all its properties are processed, if any of them were kept
+func (c *limitCounter) endService() {
+	if c.serviceProperties > 0 {
+		c.services++
+	}
+}
+
+// checks the entries of a property, and returns the indexes of the values to keep.
+// The metadata is checked first, as a whole: a property is dropped if its metadata doesn't fit the limits.
+func (c *limitCounter) property(serviceName, prop string, values, metadata []limitEntry) []int {
+	l := c.limits
+	encodedSize := c.encodedSize
+	limit := ""
+
+	switch {
+	case l.MaxPropertiesPerService > 0 && c.serviceProperties >= l.MaxPropertiesPerService:
+		limit = "MaxPropertiesPerService"
+	case l.MaxProperties > 0 && c.properties >= l.MaxProperties:
+		limit = "MaxProperties"
+	}
+
+	for _, entry := range metadata {
+		if limit != "" {
+			break
+		}
+
+		limit = c.entryLimit(entry, encodedSize)
+		encodedSize += len(entry.name) + len(entry.value)
+	}
+
+	if limit != "" {
+		c.report.add(serviceName, prop, limit)
+		return nil
+	}
+
+	var kept []int
+	for i, entry := range values {
+		if limit := c.entryLimit(entry, encodedSize); limit != "" {
+			c.report.add(serviceName, prop, limit)
+			continue
+		}
+
+		encodedSize += len(entry.name) + len(entry.value)
+		kept = append(kept, i)
+	}
+
+	if len(kept) > 0 {
+		c.encodedSize = encodedSize
+		c.serviceProperties++
+		c.properties++
+	}
+
+	return kept
+}
+
+// returns the name of a limit exceeded by an entry added to the entries of a given encoded size, or an empty string
+func (c *limitCounter) entryLimit(entry limitEntry, encodedSize int) string {
+	l := c.limits
+
+	switch {
+	case l.MaxKeyLength > 0 && len(entry.name) > l.MaxKeyLength:
+		return "MaxKeyLength"
+	case l.MaxValueLength > 0 && len(entry.value) > l.MaxValueLength:
+		return "MaxValueLength"
+	case l.MaxEncodedSize > 0 && encodedSize+len(entry.name)+len(entry.value) > l.MaxEncodedSize:
+		return "MaxEncodedSize"
+	}
+
+	return ""
+}
+
+// enforces the limits and calls the Report function if they were exceeded
+func (l *Limits) apply(props Properties, format Format) Properties {
+	if l == nil {
+		return props
+	}
+
+	result, report := l.Enforce(props, format)
+	if report.Exceeded() && l.Report != nil {
+		l.Report(report)
+	}
+
+	return result
+}
+
+func (r *LimitReport) add(serviceName, prop, limit string) {
+	r.Violations = append(r.Violations, LimitViolation{Service: serviceName, Property: prop, Limit: limit})
+}
@@ -0,0 +1,186 @@
+package servicectx
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLimits_Enforce(t *testing.T) {
+	props := New().
+		Set("a", "branch", "main").
+		Set("a", "url", "http://a").
+		Set("a", "version", "1").
+		Set("b", "branch", "main").
+		Set("c", "branch", "main")
+
+	tests := []struct {
+		name           string
+		limits         *Limits
+		wantProps      Properties
+		wantViolations []LimitViolation
+	}{
+		{
+			name:      "no limits",
+			limits:    nil,
+			wantProps: props,
+		},
+		{
+			name:      "max services",
+			limits:    &Limits{MaxServices: 2},
+			wantProps: New().Set("a", "branch", "main").Set("a", "url", "http://a").Set("a", "version", "1").Set("b", "branch", "main"),
+			wantViolations: []LimitViolation{
+				{Service: "c", Limit: "MaxServices"},
+			},
+		},
+		{
+			name:      "max properties per service",
+			limits:    &Limits{MaxPropertiesPerService: 1},
+			wantProps: New().Set("a", "branch", "main").Set("b", "branch", "main").Set("c", "branch", "main"),
+			wantViolations: []LimitViolation{
+				{Service: "a", Property: "url", Limit: "MaxPropertiesPerService"},
+				{Service: "a", Property: "version", Limit: "MaxPropertiesPerService"},
+			},
+		},
+		{
+			name:      "max properties",
+			limits:    &Limits{MaxProperties: 4},
+			wantProps: New().Set("a", "branch", "main").Set("a", "url", "http://a").Set("a", "version", "1").Set("b", "branch", "main"),
+			wantViolations: []LimitViolation{
+				{Service: "c", Property: "branch", Limit: "MaxProperties"},
+			},
+		},
+		{
+			name:      "max key length",
+			limits:    &Limits{MaxKeyLength: len("x-service-a-url")},
+			wantProps: New().Set("a", "url", "http://a"),
+			wantViolations: []LimitViolation{
+				{Service: "a", Property: "branch", Limit: "MaxKeyLength"},
+				{Service: "a", Property: "version", Limit: "MaxKeyLength"},
+				{Service: "b", Property: "branch", Limit: "MaxKeyLength"},
+				{Service: "c", Property: "branch", Limit: "MaxKeyLength"},
+			},
+		},
+		{
+			name:      "max value length",
+			limits:    &Limits{MaxValueLength: 4},
+			wantProps: New().Set("a", "branch", "main").Set("a", "version", "1").Set("b", "branch", "main").Set("c", "branch", "main"),
+			wantViolations: []LimitViolation{
+				{Service: "a", Property: "url", Limit: "MaxValueLength"},
+			},
+		},
+		{
+			name:      "max encoded size",
+			limits:    &Limits{MaxEncodedSize: len("x-service-a-branch") + len("main") + len("x-service-a-url")},
+			wantProps: New().Set("a", "branch", "main"),
+			wantViolations: []LimitViolation{
+				{Service: "a", Property: "url", Limit: "MaxEncodedSize"},
+				{Service: "a", Property: "version", Limit: "MaxEncodedSize"},
+				{Service: "b", Property: "branch", Limit: "MaxEncodedSize"},
+				{Service: "c", Property: "branch", Limit: "MaxEncodedSize"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotProps, report := tt.limits.Enforce(props, DefaultFormat)
+			require.Equal(t, tt.wantProps, gotProps)
+			require.Equal(t, tt.wantViolations, report.Violations)
+			require.Equal(t, len(tt.wantViolations) > 0, report.Exceeded())
+			require.False(t, report.Rejected)
+		})
+	}
+}
+
+func TestLimits_Reject(t *testing.T) {
+	props := New().Set("a", "branch", "main").Set("a", "url", strings.Repeat("a", 10))
+
+	gotProps, report := (&Limits{MaxValueLength: 5, Reject: true}).Enforce(props, DefaultFormat)
+	require.Empty(t, gotProps.Services())
+	require.True(t, report.Rejected)
+	require.Equal(t, `property "url" of service "a" exceeds MaxValueLength`, report.Violations[0].String())
+
+	gotProps, report = (&Limits{MaxValueLength: 10, Reject: true}).Enforce(props, DefaultFormat)
+	require.Equal(t, props, gotProps)
+	require.False(t, report.Rejected)
+}
+
+func TestSetLimits(t *testing.T) {
+	var reports []*LimitReport
+	SetLimits(&Limits{
+		MaxPropertiesPerService: 1,
+		Report: func(report *LimitReport) {
+			reports = append(reports, report)
+		},
+	})
+	defer SetLimits(nil)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("x-service-a-branch", "main")
+	req.Header.Set("x-service-a-url", "http://a")
+
+	require.Equal(t, New().Set("a", "branch", "main"), FromRequest(req))
+	require.Len(t, reports, 1)
+
+	props := New().Set("a", "branch", "main").Set("a", "url", "http://a")
+	require.Equal(t, map[string][]string{"x-service-a-branch": {"main"}}, props.Entries())
+	require.Equal(t, map[string]string{"x-service-a-branch": "main"}, props.HeaderMap())
+	require.Len(t, reports, 3)
+}
+
+func TestSetLimits_Entries(t *testing.T) {
+	var reports []*LimitReport
+	SetLimits(&Limits{
+		MaxProperties: 2,
+		Report: func(report *LimitReport) {
+			reports = append(reports, report)
+		},
+	})
+	defer SetLimits(nil)
+
+	// the metadata is counted the same way when injecting and parsing, so the same properties are kept
+	entries := New().
+		Set("a", "url", "http://a").
+		SetHops("a", "url", 2).
+		Set("b", "branch", "main").
+		SetHops("b", "branch", 2).
+		Set("c", "branch", "main").
+		Entries()
+	require.Len(t, entries, 6)
+	require.Len(t, reports, 1)
+	require.Equal(t, []LimitViolation{{Service: "c", Property: "branch", Limit: "MaxProperties"}}, reports[0].Violations)
+
+	props := FromEntries(entries)
+	require.True(t, props.Equal(New().Set("a", "url", "http://a").Set("b", "branch", "main")))
+	require.Len(t, reports, 1)
+
+	// a property is dropped along with its metadata, and orphan metadata doesn't count
+	props = FromEntries(map[string][]string{
+		"x-service-a-url.hops":    {"2"},
+		"x-service-b-branch":      {"main"},
+		"x-service-c-url":         {"http://c"},
+		"x-service-c-url.hops":    {"2"},
+		"x-service-d-url":         {"http://d"},
+		"x-service-d-url.expires": {"2030-01-02T03:04:05Z"},
+	})
+	require.True(t, props.Equal(New().Set("b", "branch", "main").Set("c", "url", "http://c")))
+	_, ok := props.Expiry("d", "url")
+	require.False(t, ok)
+	require.Len(t, reports, 2)
+	require.Equal(t, []LimitViolation{{Service: "d", Property: "url", Limit: "MaxProperties"}}, reports[1].Violations)
+
+	// the lengths are checked on the received names and values, before decryption
+	SetLimits(nil)
+	SetSealing(&Sealing{Keys: NewKeyRing("v1", []byte("secret")), Rules: []Rule{{Property: "token"}}})
+	defer SetSealing(nil)
+
+	entries = New().Set("a", "token", "short").Entries()
+	SetLimits(&Limits{MaxKeyLength: 20, MaxValueLength: 20})
+	require.Empty(t, FromEntries(entries).Services())
+	require.Empty(t, New().Set("a", "token", "short").Entries(), "the lengths of encrypted values must be checked when injecting too")
+
+	SetLimits(&Limits{MaxKeyLength: 20})
+	require.Equal(t, "short", FromEntries(entries).Get("a", "token", ""))
+	require.Empty(t, FromEntries(map[string][]string{"x-service-a-long-property": {"main"}}).Services())
+}
@@ -0,0 +1,99 @@
+package servicectx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergeStrategy resolves a conflict between an existing property and a different value of the same property being merged.
+// It returns the values to keep; returning no values removes the property.
+type MergeStrategy func(serviceName, prop string, oldValues, newValues []string) ([]string, error)
+
+// KeepExisting is a merge strategy keeping the existing values
+func KeepExisting(serviceName, prop string, oldValues, newValues []string) ([]string, error) {
+	return oldValues, nil
+}
+
+// Overwrite is a merge strategy replacing the existing values with the new ones, like Merge does
+func Overwrite(serviceName, prop string, oldValues, newValues []string) ([]string, error) {
+	return newValues, nil
+}
+
+// ErrorOnConflict is a merge strategy failing with a MergeConflictError
+func ErrorOnConflict(serviceName, prop string, oldValues, newValues []string) ([]string, error) {
+	return nil, &MergeConflictError{Service: serviceName, Property: prop, Old: oldValues, New: newValues}
+}
+
+// MergeConflictError is returned by the ErrorOnConflict merge strategy
+type MergeConflictError struct {
+	Service  string
+	Property string
+	Old      []string
+	New      []string
+}
+
+func (e *MergeConflictError) Error() string {
+	return fmt.Sprintf(
+		"servicectx: conflicting values of property %q of service %q: %q and %q",
+		e.Property,
+		e.Service,
+		strings.Join(e.Old, ","),
+		strings.Join(e.New, ","),
+	)
+}
+
+// MergeWith merges other properties, resolving conflicting values with a given strategy.
+// The properties are processed in alphabetical order, and the first error returned by the strategy stops the merge,
+// in which case the receiver is left intact. Otherwise, the receiver is modified and returned for chaining.
+//
+// The metadata of a property (see SetHops) is taken along with its value.
+func (p Properties) MergeWith(other Properties, strategy MergeStrategy) (Properties, error) {
+	merged := New()
+	var removed [][2]string
+	var err error
+
+	other.Range(func(serviceName, prop string, newValues []string) bool {
+		oldValues, exists := p.values(serviceName, prop)
+		if !exists || equalValues(oldValues, newValues) {
+			merged.Merge(other.withMetadata(serviceName, prop))
+			return true
+		}
+
+		var values []string
+		if values, err = strategy(serviceName, prop, oldValues, newValues); err != nil {
+			return false
+		}
+
+		switch {
+		case len(values) == 0:
+			removed = append(removed, [2]string{serviceName, prop})
+		case equalValues(values, newValues):
+			merged.Merge(other.withMetadata(serviceName, prop))
+		case !equalValues(values, oldValues):
+			merged.setValues(serviceName, prop, values)
+		}
+
+		return true
+	})
+
+	if err != nil {
+		return p, err
+	}
+
+	for _, key := range removed {
+		p.Delete(key[0], key[1])
+	}
+
+	return p.Merge(merged), nil
+}
+
+// returns a property along with its metadata
+func (p Properties) withMetadata(serviceName, prop string) Properties {
+	result := New()
+
+	values, _ := p.values(serviceName, prop)
+	result.setValues(serviceName, prop, values)
+	result.copyMetadata(p, serviceName, prop)
+
+	return result
+}
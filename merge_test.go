@@ -0,0 +1,74 @@
+package servicectx
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestProperties_MergeWith(t *testing.T) {
+	existing := func() Properties {
+		return New().Set("a", "url", "http://old").Set("a", "branch", "main").Set("b", "version", "1")
+	}
+	other := New().Set("a", "url", "http://new").Set("a", "branch", "main").Set("c", "version", "2")
+
+	props, err := existing().MergeWith(other, KeepExisting)
+	require.NoError(t, err)
+	require.Equal(t, New().Set("a", "url", "http://old").Set("a", "branch", "main").Set("b", "version", "1").Set("c", "version", "2"), props)
+
+	props, err = existing().MergeWith(other, Overwrite)
+	require.NoError(t, err)
+	require.Equal(t, existing().Merge(other), props)
+
+	props = existing()
+	_, err = props.MergeWith(other, ErrorOnConflict)
+	var conflict *MergeConflictError
+	require.True(t, errors.As(err, &conflict))
+	require.Equal(t, &MergeConflictError{Service: "a", Property: "url", Old: []string{"http://old"}, New: []string{"http://new"}}, conflict)
+	require.Equal(t, `servicectx: conflicting values of property "url" of service "a": "http://old" and "http://new"`, err.Error())
+	require.Equal(t, existing(), props, "properties must be left intact on error")
+
+	// no conflicts
+	props, err = existing().MergeWith(New().Set("a", "url", "http://old").Set("c", "version", "2"), ErrorOnConflict)
+	require.NoError(t, err)
+	require.Equal(t, existing().Set("c", "version", "2"), props)
+}
+
+func TestProperties_MergeWith_Callback(t *testing.T) {
+	var conflicts []string
+	strategy := func(serviceName, prop string, oldValues, newValues []string) ([]string, error) {
+		conflicts = append(conflicts, serviceName+"."+prop)
+		if prop == "tag" {
+			return append(oldValues, newValues...), nil
+		}
+
+		return nil, nil
+	}
+
+	props, err := New().
+		Set("a", "url", "http://old").
+		Set("a", "tag", "one").
+		MergeWith(New().Set("a", "url", "http://new").Set("a", "tag", "two"), strategy)
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.tag", "a.url"}, conflicts)
+	require.Equal(t, New().Add("a", "tag", "one").Add("a", "tag", "two"), props)
+}
+
+func TestProperties_MergeWith_Metadata(t *testing.T) {
+	props, err := New().
+		Set("a", "url", "http://old").
+		SetHops("a", "url", 1).
+		MergeWith(New().Set("a", "url", "http://new").SetHops("a", "url", 3), Overwrite)
+
+	require.NoError(t, err)
+	require.Equal(t, New().Set("a", "url", "http://new").SetHops("a", "url", 3), props)
+
+	props, err = New().
+		Set("a", "url", "http://old").
+		SetHops("a", "url", 1).
+		MergeWith(New().Set("a", "url", "http://new").SetHops("a", "url", 3), KeepExisting)
+
+	require.NoError(t, err)
+	require.Equal(t, New().Set("a", "url", "http://old").SetHops("a", "url", 1), props)
+}
@@ -0,0 +1,19 @@
+package servicectx
+
+import "net/http"
+
+// Middleware parses properties from each request with FromRequest and stores them in the request context,
+// so that FromContext and InjectIntoHeadersFromContext can be used by the handlers.
+// The options, such as WithPolicy, OnApplied and OnRejected, are applied to each request.
+func Middleware(next http.Handler, opts ...RequestOption) http.Handler {
+	return MiddlewareWithFormat(next, DefaultFormat, opts...)
+}
+
+// MiddlewareWithFormat parses properties of a given format from each request and stores them in the request context
+func MiddlewareWithFormat(next http.Handler, format Format, opts ...RequestOption) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := injectIntoContextFromRequest(req.Context(), req, format, opts)
+
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
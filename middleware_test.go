@@ -0,0 +1,83 @@
+package servicectx
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var got Properties
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/?x-service-api-url=http://api", nil)
+	req.Header.Set("x-service-api-branch", "main")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(t, New().Set("api", "url", "http://api").Set("api", "branch", "main"), got)
+}
+
+func TestMiddleware_Options(t *testing.T) {
+	var got, applied, rejected Properties
+	handler := Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = FromContext(r.Context())
+		}),
+		WithPolicy(&Policy{Allow: []Rule{{Service: "api"}}}),
+		WithLimits(&Limits{MaxValueLength: 10}),
+		OnApplied(func(req *http.Request, props Properties) {
+			applied = props
+		}),
+		OnRejected(func(req *http.Request, props Properties) {
+			rejected = props
+		}),
+	)
+
+	req := httptest.NewRequest("GET", "/?x-service-api-url=http://long-api-url", nil)
+	req.Header.Set("x-service-api-branch", "main")
+	req.Header.Set("x-service-billing-branch", "main")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(t, New().Set("api", "branch", "main"), got)
+	require.Equal(t, got, applied)
+	require.Equal(t, New().Set("api", "url", "http://long-api-url").Set("billing", "branch", "main"), rejected)
+}
+
+func TestMiddlewareWithFormat(t *testing.T) {
+	var got Properties
+	handler := MiddlewareWithFormat(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = FromContext(r.Context())
+		}),
+		Format{Prefix: "x-kt", Separator: "-"},
+		WithPrecedence(SourceHeader, SourceQuery),
+	)
+
+	req := httptest.NewRequest("GET", "/?x-kt-api-branch=query", nil)
+	req.Header.Set("x-kt-api-branch", "header")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(t, New().Set("api", "branch", "header"), got)
+}
+
+func TestMiddleware_PassThrough(t *testing.T) {
+	SetMode(PassThrough)
+	defer resetMode()
+
+	var got Properties
+	outgoing := http.Header{}
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+		InjectIntoHeadersFromContext(r.Context(), outgoing)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("x-service-api-branch", "main")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(t, New(), got)
+	require.Equal(t, http.Header{"X-Service-Api-Branch": {"main"}}, outgoing)
+}
@@ -0,0 +1,149 @@
+package servicectx
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Mode defines whether properties are accepted from the outside world
+type Mode int
+
+const (
+	// Enabled is the default mode: properties are parsed from all sources
+	Enabled Mode = iota
+	// Disabled makes all parse functions return empty properties, e.g. in production
+	Disabled
+	// PassThrough makes all parse functions return empty properties,
+	// but InjectIntoContextFromRequest keeps the received properties in the context,
+	// and InjectIntoHeadersFromContext passes them on to downstream services
+	PassThrough
+)
+
+// ModeEnvVar is an environment variable setting the mode: "enabled", "disabled", or "passthrough".
+// It's read on startup, unless the mode is set with SetMode.
+const ModeEnvVar = "SERVICECTX_MODE"
+
+func (m Mode) String() string {
+	switch m {
+	case Enabled:
+		return "enabled"
+	case Disabled:
+		return "disabled"
+	case PassThrough:
+		return "passthrough"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// ParseMode parses a mode name, like the one in the ModeEnvVar environment variable
+func ParseMode(name string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "enabled", "on", "true", "1":
+		return Enabled, nil
+	case "disabled", "off", "false", "0":
+		return Disabled, nil
+	case "passthrough", "pass-through":
+		return PassThrough, nil
+	default:
+		return Disabled, fmt.Errorf("servicectx: unknown mode %q", name)
+	}
+}
+
+// the mode set with SetMode or read from ModeEnvVar
+var mode struct {
+	sync.RWMutex
+	value       Mode
+	initialized bool
+}
+
+// logs a mode other than Enabled once
+var modeLogged sync.Once
+
+// reads the mode from ModeEnvVar on startup, so that a mode other than Enabled is logged then
+func init() {
+	currentMode()
+}
+
+// Logger receives the messages of the package, such as the mode it works in. *log.Logger implements it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// the logger set with SetLogger
+var logger = struct {
+	sync.RWMutex
+	value Logger
+}{
+	value: log.Default(),
+}
+
+// SetLogger sets a logger for the messages of the package. The standard logger is used by default, and nil disables logging.
+// The mode read from ModeEnvVar on startup is logged before SetLogger can be called, so it goes to the standard logger.
+func SetLogger(l Logger) {
+	logger.Lock()
+	defer logger.Unlock()
+
+	logger.value = l
+}
+
+// logs a message with the logger set with SetLogger
+func logf(format string, v ...interface{}) {
+	logger.RLock()
+	defer logger.RUnlock()
+
+	if logger.value != nil {
+		logger.value.Printf(format, v...)
+	}
+}
+
+// SetMode sets the mode, overriding the ModeEnvVar environment variable
+func SetMode(m Mode) {
+	mode.Lock()
+	defer mode.Unlock()
+
+	mode.value = m
+	mode.initialized = true
+}
+
+// returns the mode set with SetMode, or read from ModeEnvVar on the first call.
+// A mode other than Enabled is logged once: on startup if it's set with ModeEnvVar, or on the first use of the package otherwise.
+func currentMode() Mode {
+	m := loadMode()
+	if m != Enabled {
+		modeLogged.Do(func() {
+			logf("servicectx: %s mode", m)
+		})
+	}
+
+	return m
+}
+
+// returns the mode set with SetMode, or reads it from ModeEnvVar on the first call.
+// An invalid environment variable disables the package, so that a typo can't enable it in production.
+func loadMode() Mode {
+	mode.RLock()
+	if mode.initialized {
+		defer mode.RUnlock()
+		return mode.value
+	}
+	mode.RUnlock()
+
+	mode.Lock()
+	defer mode.Unlock()
+
+	if !mode.initialized {
+		value, err := ParseMode(os.Getenv(ModeEnvVar))
+		if err != nil {
+			logf("%v in %s", err, ModeEnvVar)
+		}
+
+		mode.value = value
+		mode.initialized = true
+	}
+
+	return mode.value
+}
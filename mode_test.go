@@ -0,0 +1,158 @@
+package servicectx
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// makes the mode to be read from the environment variable again
+func resetMode() {
+	mode.Lock()
+	defer mode.Unlock()
+
+	mode.initialized = false
+	modeLogged = sync.Once{}
+}
+
+// collects logged messages
+type testLogger []string
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Mode
+		wantErr bool
+	}{
+		{name: "", want: Enabled},
+		{name: "enabled", want: Enabled},
+		{name: "Disabled", want: Disabled},
+		{name: "off", want: Disabled},
+		{name: "passthrough", want: PassThrough},
+		{name: "pass-through", want: PassThrough},
+		{name: "disable", want: Disabled, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMode(tt.name)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestMode_String(t *testing.T) {
+	require.Equal(t, "enabled", Enabled.String())
+	require.Equal(t, "disabled", Disabled.String())
+	require.Equal(t, "passthrough", PassThrough.String())
+	require.Equal(t, "Mode(42)", Mode(42).String())
+}
+
+func TestModeEnvVar(t *testing.T) {
+	defer resetMode()
+
+	t.Setenv(ModeEnvVar, "disabled")
+	resetMode()
+	require.Equal(t, New(), FromQueryString("x-service-api-branch=main"))
+
+	t.Setenv(ModeEnvVar, "nonsense")
+	resetMode()
+	require.Equal(t, Disabled, currentMode())
+
+	// an explicitly set mode has a preference
+	SetMode(Enabled)
+	require.Equal(t, New().Set("api", "branch", "main"), FromQueryString("x-service-api-branch=main"))
+}
+
+func TestSetLogger(t *testing.T) {
+	var messages testLogger
+	SetLogger(&messages)
+	defer SetLogger(log.Default())
+	defer resetMode()
+
+	t.Setenv(ModeEnvVar, "nonsense")
+	resetMode()
+	require.Equal(t, Disabled, currentMode())
+	require.Equal(t, Disabled, currentMode())
+
+	SetMode(Enabled)
+	SetMode(PassThrough)
+	require.Equal(t, PassThrough, currentMode())
+	require.Equal(
+		t,
+		testLogger{`servicectx: unknown mode "nonsense" in SERVICECTX_MODE`, "servicectx: disabled mode"},
+		messages,
+		"the mode must be logged once",
+	)
+
+	SetLogger(nil)
+	resetMode()
+	require.Equal(t, Disabled, currentMode())
+	require.Len(t, messages, 2)
+
+	// the default mode is not logged
+	SetLogger(&messages)
+	t.Setenv(ModeEnvVar, "")
+	resetMode()
+	require.Equal(t, Enabled, currentMode())
+	require.Len(t, messages, 2)
+}
+
+func TestSetMode_Disabled(t *testing.T) {
+	SetMode(Disabled)
+	defer resetMode()
+
+	headers := http.Header{}
+	headers.Set("x-service-api-branch", "main")
+	req, _ := http.NewRequest("GET", "/?x-service-api-url=http://api", nil)
+	req.Header = headers
+
+	require.Equal(t, New(), FromHeaders(headers))
+	require.Equal(t, New(), FromRequest(req))
+
+	ctx := InjectIntoContextFromRequest(context.Background(), req)
+	require.Equal(t, New(), FromContext(ctx))
+
+	outgoing := http.Header{}
+	InjectIntoHeadersFromContext(ctx, outgoing)
+	require.Empty(t, outgoing)
+
+	// the properties set in application code are still propagated
+	outgoing = http.Header{}
+	InjectIntoHeadersFromContext(WithProperty(ctx, "api", "version", "2"), outgoing)
+	require.Equal(t, http.Header{"X-Service-Api-Version": {"2"}}, outgoing)
+}
+
+func TestSetMode_PassThrough(t *testing.T) {
+	SetMode(PassThrough)
+	defer resetMode()
+
+	req, _ := http.NewRequest("GET", "/?x-service-api-url=http://api", nil)
+	req.Header.Set("x-service-api-branch", "main")
+
+	require.Equal(t, New(), FromRequest(req))
+
+	ctx := InjectIntoContextFromRequest(context.Background(), req)
+	require.Equal(t, New(), FromContext(ctx), "properties must not be visible to the application")
+
+	// ...but are passed on to downstream services, along with the ones set in application code
+	outgoing := http.Header{}
+	InjectIntoHeadersFromContext(WithProperty(ctx, "api", "branch", "feature-123"), outgoing)
+	require.Equal(
+		t,
+		http.Header{
+			"X-Service-Api-Url":    {"http://api"},
+			"X-Service-Api-Branch": {"feature-123"},
+		},
+		outgoing,
+	)
+}
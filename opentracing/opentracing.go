@@ -8,8 +8,14 @@ import (
 
 // InjectIntoSpan adds properties into span's baggage
 func InjectIntoSpan(span opentracing.Span, props servicectx.Properties) {
-	for key, value := range props.HeaderMap() {
-		span.SetBaggageItem(key, value)
+	InjectIntoSpanWithFormat(span, props, servicectx.DefaultFormat)
+}
+
+// InjectIntoSpanWithFormat adds properties into span's baggage, with keys named according to a given format.
+// Multiple values of a property are encoded into a single baggage item with servicectx.JoinValues.
+func InjectIntoSpanWithFormat(span opentracing.Span, props servicectx.Properties, format servicectx.Format) {
+	for key, values := range props.EntriesWithFormat(format) {
+		span.SetBaggageItem(key, servicectx.JoinValues(values))
 	}
 }
 
@@ -17,7 +23,13 @@ func InjectIntoSpan(span opentracing.Span, props servicectx.Properties) {
 // This is convenient when the properties can be set both in application code via context and from outside world by opentracing.
 // The properties from Go context have a preference over span's context.
 func FromContextAndSpan(ctx context.Context, span opentracing.Span) servicectx.Properties {
-	return FromSpan(span).Merge(servicectx.FromContext(ctx))
+	return FromContextAndSpanWithFormat(ctx, span, servicectx.DefaultFormat)
+}
+
+// FromContextAndSpanWithFormat retrieves properties from Go context and properties of a given format from span's context.
+// The properties from Go context have a preference over span's context.
+func FromContextAndSpanWithFormat(ctx context.Context, span opentracing.Span, format servicectx.Format) servicectx.Properties {
+	return FromSpanWithFormat(span, format).Merge(servicectx.FromContext(ctx).WithSource(servicectx.SourceContext))
 }
 
 // FromSpan retrieves properties from span
@@ -25,17 +37,24 @@ func FromSpan(span opentracing.Span) servicectx.Properties {
 	return FromSpanContext(span.Context())
 }
 
+// FromSpanWithFormat retrieves properties of a given format from span
+func FromSpanWithFormat(span opentracing.Span, format servicectx.Format) servicectx.Properties {
+	return FromSpanContextWithFormat(span.Context(), format)
+}
+
 // FromSpanContext retrieves properties from span's context
 func FromSpanContext(spanCtx opentracing.SpanContext) servicectx.Properties {
-	props := servicectx.New()
+	return FromSpanContextWithFormat(spanCtx, servicectx.DefaultFormat)
+}
+
+// FromSpanContextWithFormat retrieves properties of a given format from span's context
+func FromSpanContextWithFormat(spanCtx opentracing.SpanContext, format servicectx.Format) servicectx.Properties {
+	entries := map[string][]string{}
 	spanCtx.ForeachBaggageItem(func(key, value string) bool {
-		serviceName, option, ok := servicectx.ParsePropertyName(key)
-		if ok {
-			props.Set(serviceName, option, value)
-		}
+		entries[key] = servicectx.SplitValues(value)
 
 		return true
 	})
 
-	return props
+	return servicectx.FromEntriesWithFormat(entries, format).WithSource(servicectx.SourceSpan)
 }
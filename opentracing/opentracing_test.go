@@ -55,3 +55,71 @@ func TestFromContextAndSpan(t *testing.T) {
 	require.True(t, parsedProps.HasProperty("b", "branch"))
 	require.Equal(t, "feature-123", parsedProps.Get("b", "branch", "main"))
 }
+
+func TestSpanWithFormat(t *testing.T) {
+	format := servicectx.Format{Prefix: "x-kt", Separator: "-"}
+	props := servicectx.New()
+	props.Set("a", "version", "1.0")
+
+	span := &mocktracer.MockSpan{}
+	InjectIntoSpanWithFormat(span, props, format)
+	require.Equal(t, "1.0", span.BaggageItem("x-kt-a-version"))
+	require.Empty(t, span.BaggageItem("x-service-a-version"))
+
+	require.Equal(t, props, FromSpanWithFormat(span, format))
+	require.Empty(t, FromSpan(span).Services())
+}
+
+func TestSpanMultipleValues(t *testing.T) {
+	props := servicectx.New()
+	props.Add("a", "tag", "one")
+	props.Add("a", "tag", "two|three")
+
+	span := &mocktracer.MockSpan{}
+	InjectIntoSpan(span, props)
+	require.Equal(t, "one|two%7Cthree", span.BaggageItem("x-service-a-tag"))
+
+	parsedProps := FromSpan(span)
+	require.Equal(t, []string{"one", "two|three"}, parsedProps.GetAll("a", "tag"))
+	require.Equal(t, props, parsedProps)
+}
+
+func TestFromSpan_Policy(t *testing.T) {
+	servicectx.SetPolicy(&servicectx.Policy{Deny: []servicectx.Rule{{Service: "b"}}})
+	defer servicectx.SetPolicy(nil)
+
+	props := servicectx.New()
+	props.Set("a", "version", "1.0")
+	span := &mocktracer.MockSpan{}
+	InjectIntoSpan(span, servicectx.New().Merge(props).Set("b", "branch", "feature-123"))
+
+	require.Equal(t, props, FromSpan(span))
+}
+
+func TestSpan_Signing(t *testing.T) {
+	ring := servicectx.NewKeyRing("v1", []byte("secret"))
+	servicectx.SetSigning(&servicectx.Signing{Keys: ring, Sign: true, Verify: true})
+	defer servicectx.SetSigning(nil)
+
+	props := servicectx.New()
+	props.Set("a", "version", "1.0")
+	span := &mocktracer.MockSpan{}
+	InjectIntoSpan(span, props)
+	require.NotEmpty(t, span.BaggageItem("x-service-signature"))
+	require.True(t, props.Equal(FromSpan(span)))
+
+	span.SetBaggageItem("x-service-a-version", "2.0")
+	require.Empty(t, FromSpan(span).Services())
+}
+
+func TestFromSpan_Source(t *testing.T) {
+	servicectx.SetSourceTracking(true)
+	defer servicectx.SetSourceTracking(false)
+
+	span := &mocktracer.MockSpan{}
+	InjectIntoSpan(span, servicectx.New().Set("a", "url", "http://span"))
+
+	source, ok := FromSpan(span).Source("a", "url")
+	require.True(t, ok)
+	require.Equal(t, servicectx.SourceSpan, source)
+}
@@ -8,10 +8,17 @@ import (
 
 // CreateBaggageMembers creates opentelemetry "baggage members" from properties
 func CreateBaggageMembers(props servicectx.Properties) []baggage.Member {
+	return CreateBaggageMembersWithFormat(props, servicectx.DefaultFormat)
+}
+
+// CreateBaggageMembersWithFormat creates opentelemetry "baggage members" from properties,
+// with member keys named according to a given format.
+// Multiple values of a property are encoded into a single member with servicectx.JoinValues.
+func CreateBaggageMembersWithFormat(props servicectx.Properties, format servicectx.Format) []baggage.Member {
 	var result []baggage.Member
 
-	for key, value := range props.HeaderMap() {
-		member, err := baggage.NewMember(key, value)
+	for key, values := range props.EntriesWithFormat(format) {
+		member, err := baggage.NewMember(key, servicectx.JoinValues(values))
 		if err != nil {
 			continue
 		}
@@ -24,7 +31,12 @@ func CreateBaggageMembers(props servicectx.Properties) []baggage.Member {
 
 // InjectIntoBaggage adds properties into opentelemetry baggage
 func InjectIntoBaggage(bag baggage.Baggage, props servicectx.Properties) baggage.Baggage {
-	for _, member := range CreateBaggageMembers(props) {
+	return InjectIntoBaggageWithFormat(bag, props, servicectx.DefaultFormat)
+}
+
+// InjectIntoBaggageWithFormat adds properties into opentelemetry baggage, with keys named according to a given format
+func InjectIntoBaggageWithFormat(bag baggage.Baggage, props servicectx.Properties, format servicectx.Format) baggage.Baggage {
+	for _, member := range CreateBaggageMembersWithFormat(props, format) {
 		bag, _ = bag.SetMember(member)
 	}
 
@@ -33,8 +45,14 @@ func InjectIntoBaggage(bag baggage.Baggage, props servicectx.Properties) baggage
 
 // InjectIntoContext adds the properties into OpenTelemetry Baggage, then adds the baggage into the context.
 func InjectIntoContext(ctx context.Context, props servicectx.Properties) context.Context {
+	return InjectIntoContextWithFormat(ctx, props, servicectx.DefaultFormat)
+}
+
+// InjectIntoContextWithFormat adds the properties into OpenTelemetry Baggage with keys named according to a given format,
+// then adds the baggage into the context.
+func InjectIntoContextWithFormat(ctx context.Context, props servicectx.Properties, format servicectx.Format) context.Context {
 	bag := baggage.FromContext(ctx)
-	bag = InjectIntoBaggage(bag, props)
+	bag = InjectIntoBaggageWithFormat(bag, props, format)
 	ctx = baggage.ContextWithBaggage(ctx, bag)
 
 	return ctx
@@ -42,23 +60,29 @@ func InjectIntoContext(ctx context.Context, props servicectx.Properties) context
 
 // FromBaggage retries properties from baggage
 func FromBaggage(bag baggage.Baggage) servicectx.Properties {
-	props := servicectx.New()
+	return FromBaggageWithFormat(bag, servicectx.DefaultFormat)
+}
 
-	for _, member := range bag.Members() {
-		serviceName, option, ok := servicectx.ParsePropertyName(member.Key())
-		if !ok {
-			continue
-		}
+// FromBaggageWithFormat retries properties of a given format from baggage
+func FromBaggageWithFormat(bag baggage.Baggage, format servicectx.Format) servicectx.Properties {
+	entries := map[string][]string{}
 
-		props.Set(serviceName, option, member.Value())
+	for _, member := range bag.Members() {
+		entries[member.Key()] = servicectx.SplitValues(member.Value())
 	}
 
-	return props
+	return servicectx.FromEntriesWithFormat(entries, format).WithSource(servicectx.SourceBaggage)
 }
 
 // FromContextAndBaggage retrieves properties from Go context and from baggage.
 // This is convenient when the properties can be set both in application code via context and from outside world by opentelemetry.
 // The properties from Go context have a preference over the baggage.
 func FromContextAndBaggage(ctx context.Context, bag baggage.Baggage) servicectx.Properties {
-	return FromBaggage(bag).Merge(servicectx.FromContext(ctx))
+	return FromContextAndBaggageWithFormat(ctx, bag, servicectx.DefaultFormat)
+}
+
+// FromContextAndBaggageWithFormat retrieves properties from Go context and properties of a given format from baggage.
+// The properties from Go context have a preference over the baggage.
+func FromContextAndBaggageWithFormat(ctx context.Context, bag baggage.Baggage, format servicectx.Format) servicectx.Properties {
+	return FromBaggageWithFormat(bag, format).Merge(servicectx.FromContext(ctx).WithSource(servicectx.SourceContext))
 }
@@ -8,6 +8,7 @@ import (
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/propagation"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -114,3 +115,128 @@ func TestInjectIntoContext(t *testing.T) {
 	require.Equal(t, "feature-123", bag.Member("x-service-b-branch").Value())
 	require.Equal(t, "3s", bag.Member("x-service-c-timeout").Value())
 }
+
+func TestBaggageWithFormat(t *testing.T) {
+	format := servicectx.Format{Prefix: "x-kt", Separator: "-"}
+	props := servicectx.New()
+	props.Set("a", "version", "1.0")
+
+	bag := InjectIntoBaggageWithFormat(baggage.Baggage{}, props, format)
+	require.Equal(t, "1.0", bag.Member("x-kt-a-version").Value())
+	require.Empty(t, bag.Member("x-service-a-version").Value())
+
+	require.Equal(t, props, FromBaggageWithFormat(bag, format))
+	require.Empty(t, FromBaggage(bag).Services())
+}
+
+func TestBaggageMultipleValues(t *testing.T) {
+	props := servicectx.New()
+	props.Add("a", "tag", "one")
+	props.Add("a", "tag", "two, three")
+	props.Set("b", "branch", "feature-123")
+
+	bag := InjectIntoBaggage(baggage.Baggage{}, props)
+	require.Equal(t, "one|two%2C%20three", bag.Member("x-service-a-tag").Value())
+	require.Equal(t, "feature-123", bag.Member("x-service-b-branch").Value())
+
+	parsedProps := FromBaggage(bag)
+	require.Equal(t, []string{"one", "two, three"}, parsedProps.GetAll("a", "tag"))
+	require.Equal(t, "one", parsedProps.Get("a", "tag", ""))
+	require.Equal(t, props, parsedProps)
+}
+
+func TestFromBaggage_Policy(t *testing.T) {
+	servicectx.SetPolicy(&servicectx.Policy{Deny: []servicectx.Rule{{Service: "b"}}})
+	defer servicectx.SetPolicy(nil)
+
+	props := servicectx.New()
+	props.Set("a", "version", "1.0")
+	bag := InjectIntoBaggage(baggage.Baggage{}, servicectx.New().Merge(props).Set("b", "branch", "feature-123"))
+
+	require.Equal(t, props, FromBaggage(bag))
+}
+
+func TestBaggage_Signing(t *testing.T) {
+	ring := servicectx.NewKeyRing("v1", []byte("secret"))
+	servicectx.SetSigning(&servicectx.Signing{Keys: ring, Sign: true, Verify: true})
+	defer servicectx.SetSigning(nil)
+
+	props := servicectx.New()
+	props.Set("a", "version", "1.0")
+	bag := InjectIntoBaggage(baggage.Baggage{}, props)
+	require.NotEmpty(t, bag.Member("x-service-signature").Value())
+	require.True(t, props.Equal(FromBaggage(bag)))
+
+	member, _ := baggage.NewMember("x-service-a-version", "2.0")
+	bag, _ = bag.SetMember(member)
+	require.Empty(t, FromBaggage(bag).Services())
+}
+
+func TestBaggage_Sealing(t *testing.T) {
+	ring := servicectx.NewKeyRing("v1", []byte("secret"))
+	servicectx.SetSealing(&servicectx.Sealing{Keys: ring, Rules: []servicectx.Rule{{Property: "token"}}})
+	defer servicectx.SetSealing(nil)
+
+	props := servicectx.New()
+	props.Set("a", "token", "test-account-token")
+	members := CreateBaggageMembers(props)
+	require.Len(t, members, 1)
+	require.True(t, servicectx.IsSealed(members[0].Value()))
+
+	bag, _ := baggage.New(members...)
+	require.Equal(t, props, FromBaggage(bag))
+}
+
+func TestBaggage_ConsumeAtTarget(t *testing.T) {
+	servicectx.SetLocalService("a")
+	defer servicectx.SetLocalService("")
+
+	props := servicectx.New().
+		Set("a", "url", "http://a").
+		SetConsumeAtTarget("a", "url").
+		Set("b", "url", "http://b")
+
+	members := CreateBaggageMembers(props)
+	require.Len(t, members, 1)
+	require.Equal(t, "x-service-b-url", members[0].Key())
+}
+
+func TestFromContextAndBaggage_Source(t *testing.T) {
+	servicectx.SetSourceTracking(true)
+	defer servicectx.SetSourceTracking(false)
+
+	bag, _ := baggage.Parse("x-service-a-url=http://baggage,x-service-a-branch=main")
+	ctx := servicectx.New().Set("a", "url", "http://context").InjectIntoContext(context.Background())
+
+	props := FromContextAndBaggage(ctx, bag)
+	source, _ := props.Source("a", "url")
+	require.Equal(t, servicectx.SourceContext, source)
+	source, _ = props.Source("a", "branch")
+	require.Equal(t, servicectx.SourceBaggage, source)
+}
+
+func TestTransport_BaggageCarrier(t *testing.T) {
+	var received servicectx.Properties
+	var members int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.Baggage{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		members = baggage.FromContext(ctx).Len()
+		received = FromBaggage(baggage.FromContext(ctx))
+	}))
+	defer server.Close()
+
+	props := servicectx.New().
+		Set("api", "query", "a+b").
+		Add("api", "tag", "x|y").
+		Add("api", "tag", "50%")
+	req, _ := http.NewRequestWithContext(props.InjectIntoContext(context.Background()), "GET", server.URL, nil)
+	req.Header.Set("baggage", "userId=42")
+
+	client := &http.Client{Transport: &servicectx.Transport{Carrier: servicectx.BaggageCarrier}}
+	_, err := client.Do(req)
+	require.NoError(t, err)
+
+	require.Equal(t, 3, members, "unrelated baggage members must be kept")
+	require.Equal(t, "a+b", received.Get("api", "query", ""))
+	require.Equal(t, []string{"x|y", "50%"}, received.GetAll("api", "tag"))
+}
@@ -0,0 +1,118 @@
+package servicectx
+
+import (
+	"path"
+	"sync"
+)
+
+// Rule matches properties by a service name and a property name.
+// Both are glob patterns as in path.Match, e.g. "billing" and "url*"; an empty pattern matches anything.
+type Rule struct {
+	Service  string
+	Property string
+}
+
+// Policy defines which incoming properties are accepted
+type Policy struct {
+	// Allow lists the accepted properties. If empty, all properties not denied are accepted.
+	Allow []Rule
+	// Deny lists the rejected properties, even if they are allowed.
+	// A property of AllServices applies to every service, so it is rejected if a rule denies it for any service.
+	Deny []Rule
+	// MaxValueLength is the maximum length of a property value in bytes; unlimited if zero
+	MaxValueLength int
+}
+
+// the policy set with SetPolicy
+var policy struct {
+	sync.RWMutex
+	value *Policy
+}
+
+// SetPolicy sets a policy applied to properties parsed from all sources:
+// FromRequest, FromHeaders, FromQueryValues, FromEntries, and the OpenTelemetry/OpenTracing extractors.
+// A nil policy (the default) accepts all properties.
+func SetPolicy(p *Policy) {
+	policy.Lock()
+	defer policy.Unlock()
+
+	policy.value = p
+}
+
+// returns the policy set with SetPolicy
+func currentPolicy() *Policy {
+	policy.RLock()
+	defer policy.RUnlock()
+
+	return policy.value
+}
+
+// Allows checks if a property value is accepted by the policy
+func (p *Policy) Allows(serviceName, prop, value string) bool {
+	if p == nil {
+		return true
+	}
+
+	if p.MaxValueLength > 0 && len(value) > p.MaxValueLength {
+		return false
+	}
+
+	serviceName = sanitizeServiceName(serviceName)
+	if len(p.Allow) > 0 && !matchesAny(p.Allow, serviceName, prop) {
+		return false
+	}
+
+	if serviceName == AllServices {
+		for _, rule := range p.Deny {
+			if matchPattern(rule.Property, prop) {
+				return false
+			}
+		}
+	}
+
+	return !matchesAny(p.Deny, serviceName, prop)
+}
+
+// Apply returns a copy of properties with only the values accepted by the policy.
+// Property metadata (see SetHops) is kept along with the property itself.
+func (p *Policy) Apply(props Properties) Properties {
+	result := New()
+
+	props.each(func(serviceName, prop string, values []string) {
+		for _, value := range values {
+			if p.Allows(serviceName, prop, value) {
+				result.addValue(serviceName, prop, value)
+			}
+		}
+
+		if result.HasProperty(serviceName, prop) {
+			result.copyMetadata(props, serviceName, prop)
+		}
+	})
+
+	return result
+}
+
+// Matches checks if a rule matches a property
+func (r Rule) Matches(serviceName, prop string) bool {
+	return matchPattern(sanitizeServiceName(r.Service), serviceName) && matchPattern(r.Property, prop)
+}
+
+func matchesAny(rules []Rule, serviceName, prop string) bool {
+	for _, rule := range rules {
+		if rule.Matches(serviceName, prop) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchPattern(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
@@ -0,0 +1,81 @@
+package servicectx
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPolicy_Allows(t *testing.T) {
+	var nilPolicy *Policy
+	require.True(t, nilPolicy.Allows("api", "url", "http://api"))
+	require.True(t, (&Policy{}).Allows("api", "url", "http://api"))
+
+	policy := &Policy{
+		Allow: []Rule{
+			{Service: "billing", Property: "branch"},
+			{Service: "api", Property: "log-*"},
+			{Service: "user-*"},
+		},
+		Deny: []Rule{
+			{Property: "log-format"},
+		},
+		MaxValueLength: 10,
+	}
+
+	require.True(t, policy.Allows("billing", "branch", "main"))
+	require.False(t, policy.Allows("billing", "url", "http://a"))
+	require.True(t, policy.Allows("api", "log-level", "debug"))
+	require.False(t, policy.Allows("api", "log-format", "json"), "a denied property must be rejected even if it's allowed")
+	require.True(t, policy.Allows("userprofile", "anything", "value"))
+	require.True(t, policy.Allows("user-profile", "anything", "value"))
+	require.False(t, policy.Allows("billing", "branch", strings.Repeat("a", 11)))
+
+	props := New().
+		Set("billing", "branch", "main").
+		Set("billing", "url", "http://billing").
+		Add("api", "log-level", "debug").
+		Add("api", "log-level", "extremely-verbose")
+
+	require.Equal(
+		t,
+		New().Set("billing", "branch", "main").Set("api", "log-level", "debug"),
+		policy.Apply(props),
+	)
+}
+
+func TestSetPolicy(t *testing.T) {
+	SetPolicy(&Policy{Allow: []Rule{{Service: "api"}}})
+	defer SetPolicy(nil)
+
+	req, _ := http.NewRequest("GET", "/?x-service-api-version=2&x-service-billing-url=http://evil", nil)
+	req.Header.Set("x-service-api-branch", "feature-123")
+	req.Header.Set("x-service-billing-branch", "feature-123")
+
+	require.Equal(
+		t,
+		New().Set("api", "version", "2").Set("api", "branch", "feature-123"),
+		FromRequest(req),
+	)
+	require.Equal(t, New().Set("api", "branch", "feature-123"), FromHeaders(req.Header))
+	require.Equal(t, New().Set("api", "version", "2"), FromQueryValues(req.URL.Query()))
+
+	props := New().Set("billing", "url", "http://billing")
+	require.Equal(t, "http://billing", props.Get("billing", "url", ""), "properties set in code must not be filtered")
+}
+
+func TestPolicy_AllServices(t *testing.T) {
+	policy := &Policy{Deny: []Rule{{Service: "billing", Property: "url"}}}
+
+	require.False(t, policy.Allows("all", "url", "http://evil"), "a property of all services must be denied if it's denied for any service")
+	require.True(t, policy.Allows("all", "branch", "main"))
+	require.True(t, policy.Allows("api", "url", "http://api"))
+
+	SetPolicy(policy)
+	defer SetPolicy(nil)
+
+	props := FromQueryString("x-service-all-url=http://evil&x-service-all-branch=main")
+	require.Equal(t, "", props.Get("billing", "url", ""))
+	require.Equal(t, "main", props.Get("billing", "branch", ""))
+}
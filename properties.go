@@ -3,17 +3,42 @@
 package servicectx
 
 import (
+	"encoding/json"
 	"net/http"
-	"strconv"
-	"strings"
+	"sort"
 	"time"
 )
 
-// Values key => value properties
+// Values key => value properties.
+// Only the first value of a multi-valued property is kept here; GetAll and Add should be used to access all of them.
 type Values map[string]string
 
-// Properties grouped by a service name
-type Properties map[string]Values
+// Properties grouped by a service name.
+// All values and metadata of properties are kept apart from the first values, so the properties can only be accessed
+// through the methods. An instance must be created with New or parsed from a carrier.
+type Properties struct {
+	// the first values of properties by service names
+	services map[string]Values
+	// all values of multi-valued properties
+	multi map[propertyKey][]string
+	// metadata attributes of properties (see SetHops), and the signature of received properties (see Signing)
+	metadata map[metaKey]string
+	// the sources of properties in the order they were merged (see SetSourceTracking)
+	sources map[propertyKey][]Source
+}
+
+// identifies a property of a service
+type propertyKey struct {
+	service string
+	prop    string
+}
+
+// identifies a metadata attribute of a property
+type metaKey struct {
+	attribute string
+	service   string
+	prop      string
+}
 
 // NamePrefix a prefix at the beginning of a property name indicating it belongs to this package
 const NamePrefix = "x-service"
@@ -22,160 +47,495 @@ const Separator = "-"
 // ParsePropertyName parses a string like "x-service-api-branch" into service name ("api"),
 // property name ("branch"), and a boolean success flag
 func ParsePropertyName(name string) (serviceName, option string, ok bool) {
-	name = strings.ToLower(name)
-
-	if !strings.HasPrefix(name, NamePrefix) {
-		return "", "", false
-	}
-
-	name = strings.TrimPrefix(name, NamePrefix+Separator)
-	parts := strings.SplitN(name, Separator, 2)
-
-	if len(parts) < 2 {
-		return "", "", false
-	}
-
-	return parts[0], parts[1], true
+	return DefaultFormat.ParsePropertyName(name)
 }
 
 // GetPropertyName builds a string from service name and property name
 func GetPropertyName(serviceName, option string) string {
-	return NamePrefix + Separator + serviceName + Separator + option
+	return DefaultFormat.GetPropertyName(serviceName, option)
 }
 
 // New constructs a new properties instance
 func New() Properties {
-	return Properties{}
+	return Properties{
+		services: map[string]Values{},
+		multi:    map[propertyKey][]string{},
+		metadata: map[metaKey]string{},
+		sources:  map[propertyKey][]Source{},
+	}
 }
 
 // HasService checks if there are options for a given service
 func (p Properties) HasService(serviceName string) bool {
-	serviceName = sanitizeServiceName(serviceName)
-	_, ok := p[serviceName]
+	_, ok := p.services[p.serviceKey(serviceName)]
 	return ok
 }
 
-// HasProperty checks if a given property exists for a service
+// HasProperty checks if a given property exists for a service, without falling back to AllServices
 func (p Properties) HasProperty(serviceName, option string) bool {
-	serviceName = sanitizeServiceName(serviceName)
-	if service, ok := p[serviceName]; ok {
-		if _, ok := service[option]; ok {
-			return true
-		}
-	}
+	_, ok := p.services[p.serviceKey(serviceName)][option]
 
-	return false
+	return ok
 }
 
-// GetByService returns all options for a given service
+// GetByService returns a copy of all options for a given service, or nil if there are no such options
 func (p Properties) GetByService(serviceName string) Values {
-	serviceName = sanitizeServiceName(serviceName)
+	values, ok := p.services[p.serviceKey(serviceName)]
+	if !ok {
+		return nil
+	}
+
+	result := make(Values, len(values))
+	for key, value := range values {
+		result[key] = value
+	}
 
-	return p[serviceName]
+	return result
 }
 
-// Get returns an property value for a given service
+// Get returns an property value for a given service.
+// If the property has multiple values, the first one is returned.
 func (p Properties) Get(serviceName, prop, defaultValue string) string {
-	serviceName = sanitizeServiceName(serviceName)
-	if service, ok := p[serviceName]; ok {
-		if value, ok := service[prop]; ok {
-			return value
-		}
+	if value, ok := p.Lookup(serviceName, prop); ok {
+		return value
 	}
 
 	return defaultValue
 }
 
-// GetInt returns a property value for a given service as an integer
-func (p Properties) GetInt(serviceName, prop string, defaultValue int) int {
-	valueStr := p.Get(serviceName, prop, "")
-	if len(valueStr) == 0 {
-		return defaultValue
-	}
+// Lookup returns a property value for a given service and whether the property exists.
+// If the property has multiple values, the first one is returned.
+// If the service has no such property, the property of AllServices is used.
+func (p Properties) Lookup(serviceName, prop string) (string, bool) {
+	return p.first(p.resolveService(serviceName, prop), prop)
+}
+
+// GetAll returns all values of a property for a given service, or nil if there is no such property.
+// If the service has no such property, the property of AllServices is used.
+func (p Properties) GetAll(serviceName, prop string) []string {
+	values, _ := p.values(p.resolveService(serviceName, prop), prop)
 
-	value, err := strconv.Atoi(valueStr)
-	if err != nil {
-		return defaultValue
+	return values
+}
+
+// returns a sanitized service name, or AllServices if the service has no such property.
+// Property metadata, like "url.hops", is resolved along with the property itself.
+func (p Properties) resolveService(serviceName, prop string) string {
+	serviceName = p.serviceKey(serviceName)
+
+	if _, ok := p.services[serviceName][prop]; !ok {
+		if _, ok := p.services[AllServices][prop]; ok {
+			return AllServices
+		}
 	}
 
-	return value
+	return serviceName
+}
+
+// GetInt returns a property value for a given service as an integer
+func (p Properties) GetInt(serviceName, prop string, defaultValue int) int {
+	return Get(p, serviceName, prop, defaultValue)
 }
 
 // GetDuration returns a property value for a given service as time.Duration
 func (p Properties) GetDuration(serviceName, prop string, defaultValue time.Duration) time.Duration {
-	valueStr := p.Get(serviceName, prop, "")
-	if len(valueStr) == 0 {
-		return defaultValue
+	return Get(p, serviceName, prop, defaultValue)
+}
+
+// GetBool returns a property value for a given service as boolean
+func (p Properties) GetBool(serviceName, prop string, defaultValue bool) bool {
+	return Get(p, serviceName, prop, defaultValue)
+}
+
+// LookupInt returns a property value for a given service as an integer, and whether a non-empty property was found.
+// If the value is not a valid integer, a *ParseError is returned.
+func (p Properties) LookupInt(serviceName, prop string) (int, bool, error) {
+	return Lookup[int](p, serviceName, prop)
+}
+
+// LookupDuration returns a property value for a given service as time.Duration, and whether a non-empty property was found.
+// If the value is not a valid duration, a *ParseError is returned.
+func (p Properties) LookupDuration(serviceName, prop string) (time.Duration, bool, error) {
+	return Lookup[time.Duration](p, serviceName, prop)
+}
+
+// LookupBool returns a property value for a given service as boolean, and whether a non-empty property was found.
+// If the value is not a valid boolean, a *ParseError is returned.
+func (p Properties) LookupBool(serviceName, prop string) (bool, bool, error) {
+	return Lookup[bool](p, serviceName, prop)
+}
+
+// Set sets a property value for a given service
+func (p Properties) Set(serviceName, prop, value string) Properties {
+	p.setValues(p.serviceKey(serviceName), prop, []string{value})
+
+	return p
+}
+
+// Add appends a value to a property of a given service, keeping its existing values
+func (p Properties) Add(serviceName, prop, value string) Properties {
+	p.addValue(p.serviceKey(serviceName), prop, value)
+
+	return p
+}
+
+// returns a key of a service: its exact name if there is such a service
+// (e.g. a dashed name received as "user--profile"), otherwise a sanitized one
+func (p Properties) serviceKey(serviceName string) string {
+	if _, ok := p.services[serviceName]; ok {
+		return serviceName
 	}
 
-	value, err := time.ParseDuration(valueStr)
-	if err != nil {
-		return defaultValue
+	return sanitizeServiceName(serviceName)
+}
+
+// appends a value to a property of a service with an exact name
+func (p Properties) addValue(serviceName, prop, value string) {
+	values, _ := p.values(serviceName, prop)
+	p.setValues(serviceName, prop, append(values, value))
+}
+
+// returns the first value of a property of a service with an exact name
+func (p Properties) first(serviceName, prop string) (string, bool) {
+	value, ok := p.services[serviceName][prop]
+
+	return value, ok
+}
+
+// returns all values of a property of a service with an exact name
+func (p Properties) values(serviceName, prop string) ([]string, bool) {
+	value, ok := p.first(serviceName, prop)
+	if !ok {
+		return nil, false
 	}
 
-	return value
+	if values, ok := p.multi[propertyKey{serviceName, prop}]; ok {
+		return append([]string(nil), values...), true
+	}
+
+	return []string{value}, true
 }
 
-// GetBool returns a property value for a given service as boolean
-func (p Properties) GetBool(serviceName, prop string, defaultValue bool) bool {
-	valueStr := p.Get(serviceName, prop, "")
-	if len(valueStr) == 0 {
-		return defaultValue
+// replaces all values of a property of a service with an exact name
+func (p Properties) setValues(serviceName, prop string, values []string) {
+	if len(values) == 0 {
+		return
 	}
 
-	value, err := strconv.ParseBool(valueStr)
-	if err != nil {
-		return defaultValue
+	if _, ok := p.services[serviceName]; !ok {
+		p.services[serviceName] = Values{}
 	}
 
-	return value
+	p.services[serviceName][prop] = values[0]
+
+	if len(values) > 1 {
+		p.multi[propertyKey{serviceName, prop}] = append([]string(nil), values...)
+	} else {
+		delete(p.multi, propertyKey{serviceName, prop})
+	}
 }
 
-// Set sets a property value for a given service
-func (p Properties) Set(serviceName, prop, value string) Properties {
-	serviceName = sanitizeServiceName(serviceName)
-	if _, ok := p[serviceName]; !ok {
-		p[serviceName] = map[string]string{}
+// removes a property of a service with an exact name, along with its values and metadata
+func (p Properties) deleteProperty(serviceName, prop string) {
+	delete(p.services[serviceName], prop)
+	if values, ok := p.services[serviceName]; ok && len(values) == 0 {
+		delete(p.services, serviceName)
 	}
 
-	p[serviceName][prop] = value
+	delete(p.multi, propertyKey{serviceName, prop})
+	delete(p.sources, propertyKey{serviceName, prop})
+	for attribute := range metaAttributes {
+		p.deleteHidden(attribute, serviceName, prop)
+	}
+}
 
-	return p
+// returns a metadata attribute of a property, or the signature if the service and property names are empty
+func (p Properties) hidden(attribute, serviceName, prop string) (string, bool) {
+	value, ok := p.metadata[metaKey{attribute, serviceName, prop}]
+
+	return value, ok
+}
+
+// sets a metadata attribute of a property, or the signature if the service and property names are empty
+func (p Properties) setHidden(attribute, serviceName, prop, value string) {
+	p.metadata[metaKey{attribute, serviceName, prop}] = value
+}
+
+// removes a metadata attribute of a property, or the signature if the service and property names are empty
+func (p Properties) deleteHidden(attribute, serviceName, prop string) {
+	delete(p.metadata, metaKey{attribute, serviceName, prop})
 }
 
 // HeaderMap returns options as a map of HTTP headers
 func (p Properties) HeaderMap() map[string]string {
+	return p.HeaderMapWithFormat(DefaultFormat)
+}
+
+// HeaderMapWithFormat returns options as a map of HTTP headers named according to a given format.
+// Only the first value of multi-valued properties is included; use EntriesWithFormat to get all of them.
+// Only the Forwardable properties within the limits set with SetLimits are included,
+// and the values matching the rules set with SetSealing are encrypted.
+func (p Properties) HeaderMapWithFormat(format Format) map[string]string {
 	result := map[string]string{}
+	sealing := currentSealing()
+	p = currentLimits().apply(p.Forwardable(), format)
+
+	p.eachEntry(func(service, key string, values []string) {
+		if value, ok := sealing.seal(service, key, values[0]); ok {
+			result[format.GetPropertyName(service, key)] = value
+		}
+	})
+
+	return result
+}
+
+// Entries returns properties as a map of property names and all their values
+func (p Properties) Entries() map[string][]string {
+	return p.EntriesWithFormat(DefaultFormat)
+}
+
+// EntriesWithFormat returns properties as a map of property names, named according to a given format, and all their values.
+// Only the Forwardable properties within the limits set with SetLimits are included,
+// and the values matching the rules set with SetSealing are encrypted.
+// If signing is enabled with SetSigning, a signature of the properties is added under the format's SignatureName.
+func (p Properties) EntriesWithFormat(format Format) map[string][]string {
+	result := map[string][]string{}
+	sealing := currentSealing()
+	received, _ := p.hidden(signatureAttribute, "", "")
+	p = currentLimits().apply(p.Forwardable(), format)
+
+	p.eachEntry(func(service, key string, values []string) {
+		sealedValues := make([]string, 0, len(values))
+
+		for _, value := range values {
+			if value, ok := sealing.seal(service, key, value); ok {
+				sealedValues = append(sealedValues, value)
+			}
+		}
 
-	for service, props := range p {
-		for key, value := range props {
-			result[GetPropertyName(service, key)] = value
+		if len(sealedValues) > 0 {
+			result[format.GetPropertyName(service, key)] = sealedValues
+		}
+	})
+
+	if signing := currentSigning(); signing != nil && len(result) > 0 {
+		if signature := signing.signature(p, received); signature != "" {
+			result[format.SignatureName()] = []string{signature}
 		}
 	}
 
 	return result
 }
 
+// FromEntries parses properties from a map of property names and their values,
+// such as http.Header, url.Values, or a map collected from any other carrier
+func FromEntries(entries map[string][]string) Properties {
+	return FromEntriesWithFormat(entries, DefaultFormat)
+}
+
+// FromEntriesWithFormat parses properties of a given format from a map of property names and their values.
+// Encrypted values are decrypted with a key ring set with SetSealing, and dropped if they can't be decrypted.
+// Repeated values are kept according to the format's DuplicatePolicy.
+// Only the values accepted by the policy set with SetPolicy and within the limits set with SetLimits are kept.
+// If signature verification is enabled with SetSigning, unsigned or tampered properties are dropped altogether.
+// Expired properties and the ones with no hops left are dropped, and the hop counters of the others are decremented.
+// If the package is disabled with SetMode or ModeEnvVar, empty properties are returned.
+func FromEntriesWithFormat(entries map[string][]string, format Format) Properties {
+	if currentMode() != Enabled {
+		return New()
+	}
+
+	return extractEntries(entries, format)
+}
+
+// parses properties like FromEntriesWithFormat does, regardless of the mode
+func extractEntries(entries map[string][]string, format Format) Properties {
+	entries = currentLimits().applyEntries(entries, format)
+	props := parseEntries(entries, format)
+	signing := currentSigning()
+	signature := signatureFromEntries(entries, format)
+
+	if signing != nil && signing.Verify {
+		if err := signing.verify(props, signature, time.Now()); err != nil {
+			return New()
+		}
+	}
+
+	props = props.withoutExpired(time.Now(), true)
+	policy := currentPolicy()
+	result := New()
+
+	props.each(func(serviceName, prop string, values []string) {
+		for _, value := range format.Duplicates.apply(values) {
+			if policy.Allows(serviceName, prop, value) {
+				result.addValue(serviceName, prop, value)
+			}
+		}
+
+		if result.HasProperty(serviceName, prop) {
+			result.copyMetadata(props, serviceName, prop)
+		}
+	})
+
+	result = currentLimits().apply(result, format)
+
+	// a verified signature is kept to be forwarded along with the properties (see Signing)
+	if signing != nil && signing.Verify && signature != "" && len(result.Services()) > 0 {
+		result.setHidden(signatureAttribute, "", "", signature)
+	}
+
+	return result
+}
+
+// parses all properties of a given format from a map of property names and their values,
+// decrypting the values encrypted with a key ring set with SetSealing.
+// The entries with metadata attributes (e.g. "url.hops") are parsed as metadata of the respective properties,
+// except for the sources, which are never propagated.
+func parseEntries(entries map[string][]string, format Format) Properties {
+	props := New()
+	metadata := New()
+	sealing := currentSealing()
+
+	for name, values := range entries {
+		serviceName, option, ok := format.ParsePropertyName(name)
+		if !ok {
+			continue
+		}
+
+		for _, value := range values {
+			value, ok := sealing.unseal(serviceName, option, value)
+			if !ok {
+				continue
+			}
+
+			if prop, attribute, isMeta := splitMetaName(option); !isMeta {
+				props.addValue(serviceName, option, value)
+			} else if attribute != SourceAttribute {
+				metadata.setHidden(attribute, serviceName, prop, value)
+			}
+		}
+	}
+
+	props.each(func(serviceName, prop string, _ []string) {
+		props.copyMetadata(metadata, serviceName, prop)
+	})
+
+	return props
+}
+
 // InjectIntoHeaders adds property headers to http.Header
 func (p Properties) InjectIntoHeaders(headers http.Header) {
-	for name, value := range p.HeaderMap() {
-		headers.Set(name, value)
+	p.InjectIntoHeadersWithFormat(headers, DefaultFormat)
+}
+
+// InjectIntoHeadersWithFormat adds property headers named according to a given format to http.Header
+func (p Properties) InjectIntoHeadersWithFormat(headers http.Header, format Format) {
+	for name, values := range p.EntriesWithFormat(format) {
+		headers.Del(name)
+		for _, value := range values {
+			headers.Add(name, value)
+		}
 	}
 }
 
 // Merge merges two sets of properties. The receiver is modified and returned for chaining.
+// A property is replaced along with its metadata, such as its hops, and its source is added to the sources of the replaced one.
 func (p Properties) Merge(other Properties) Properties {
-	for serviceName, values := range other {
+	other.each(func(serviceName, prop string, values []string) {
+		sources := p.history(serviceName, prop)
+		p.setValues(serviceName, prop, values)
+		p.copyMetadata(other, serviceName, prop)
+
+		if otherSources := other.history(serviceName, prop); len(otherSources) > 0 {
+			p.sources[propertyKey{serviceName, prop}] = append(sources, otherSources...)
+		}
+	})
+
+	return p.keepSignature(other)
+}
+
+// Clone returns a deep copy of properties
+func (p Properties) Clone() Properties {
+	result := New()
+	for serviceName, values := range p.services {
+		result.services[serviceName] = make(Values, len(values))
 		for key, value := range values {
-			p.Set(serviceName, key, value)
+			result.services[serviceName][key] = value
 		}
 	}
 
-	return p
+	for key, values := range p.multi {
+		result.multi[key] = append([]string(nil), values...)
+	}
+
+	for key, value := range p.metadata {
+		result.metadata[key] = value
+	}
+
+	for key, sources := range p.sources {
+		result.sources[key] = append([]Source(nil), sources...)
+	}
+
+	return result
+}
+
+// MarshalJSON encodes properties as an object of services and their options, like GetByService returns them.
+// Only the first value of multi-valued properties is included, and metadata is omitted.
+func (p Properties) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.services)
 }
 
-// removes dashes from service names,
-// so that "my-service" and "myservice" are treated equally.
-func sanitizeServiceName(name string) string {
-	return strings.ReplaceAll(name, "-", "")
+// UnmarshalJSON decodes properties encoded with MarshalJSON
+func (p *Properties) UnmarshalJSON(data []byte) error {
+	var services map[string]Values
+	if err := json.Unmarshal(data, &services); err != nil {
+		return err
+	}
+
+	*p = New()
+	for serviceName, values := range services {
+		for key, value := range values {
+			p.setValues(serviceName, key, []string{value})
+		}
+	}
+
+	return nil
+}
+
+// calls fn for each entry of properties as they are propagated, in alphabetical order of services and property names:
+// each property is followed by its metadata, e.g. "url" and "url.hops".
+// The sources are not propagated, and neither are the properties with reserved names like "url.hops".
+func (p Properties) eachEntry(fn func(serviceName, name string, values []string)) {
+	p.each(func(serviceName, prop string, values []string) {
+		if _, _, isMeta := splitMetaName(prop); isMeta {
+			return
+		}
+
+		fn(serviceName, prop, values)
+
+		for _, attribute := range propagatedAttributes {
+			if value, ok := p.hidden(attribute, serviceName, prop); ok {
+				fn(serviceName, metaName(prop, attribute), []string{value})
+			}
+		}
+	})
+}
+
+// calls fn for each property with all its values, in alphabetical order of services and property names
+func (p Properties) each(fn func(serviceName, prop string, values []string)) {
+	p.Range(func(serviceName, prop string, values []string) bool {
+		fn(serviceName, prop, values)
+		return true
+	})
+}
+
+// returns sorted keys of a map
+func sortedKeys[V any](m map[string]V) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+
+	return result
 }
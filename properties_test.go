@@ -1,6 +1,7 @@
 package servicectx
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
@@ -158,3 +159,54 @@ func TestProperties_Merge(t *testing.T) {
 		a.Merge(b).HeaderMap(),
 	)
 }
+
+func TestProperties_MultipleValues(t *testing.T) {
+	props := New()
+	require.Nil(t, props.GetAll("api", "tag"))
+
+	props.Add("api", "tag", "one")
+	require.Equal(t, []string{"one"}, props.GetAll("api", "tag"))
+
+	props.Add("api", "tag", "two")
+	require.Equal(t, []string{"one", "two"}, props.GetAll("api", "tag"))
+	require.Equal(t, "one", props.Get("api", "tag", ""), "the first value must be returned by Get")
+	require.Equal(t, map[string]string{"x-service-api-tag": "one"}, props.HeaderMap())
+	require.Equal(t, map[string][]string{"x-service-api-tag": {"one", "two"}}, props.Entries())
+
+	props.Set("api", "tag", "three")
+	require.Equal(t, []string{"three"}, props.GetAll("api", "tag"), "Set must replace all values")
+
+	require.Equal(
+		t,
+		props,
+		FromEntries(map[string][]string{"x-service-api-tag": {"three"}, "unknown": {"value"}}),
+	)
+}
+
+func TestProperties_MultipleValuesWithZeroBytes(t *testing.T) {
+	props := FromQueryString("x-service-api-tag=a%00b&x-service-api-tag=c")
+	require.Equal(t, "a\x00b", props.Get("api", "tag", ""))
+	require.Equal(t, []string{"a\x00b", "c"}, props.GetAll("api", "tag"))
+	require.Equal(t, []string{"api"}, props.Services())
+	require.Equal(t, Values{"tag": "a\x00b"}, props.GetByService("api"))
+
+	props = FromQueryString("x-service-api-tag=a%00b")
+	require.Equal(t, []string{"a\x00b"}, props.GetAll("api", "tag"))
+	require.False(t, props.Equal(New().Add("api", "tag", "a").Add("api", "tag", "b")))
+
+	props = New().Add("api", "tag", "one").Add("api", "tag", "two")
+	props.GetByService("api")["tag"] = "three"
+	require.Equal(t, []string{"one", "two"}, props.GetAll("api", "tag"), "GetByService must return a copy")
+}
+
+func TestProperties_JSON(t *testing.T) {
+	props := New().Set("api", "url", "http://api").SetHops("api", "url", 2).Add("api", "tag", "one").Add("api", "tag", "two")
+
+	data, err := json.Marshal(props)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"api":{"tag":"one","url":"http://api"}}`, string(data))
+
+	var decoded Properties
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, New().Set("api", "url", "http://api").Set("api", "tag", "one"), decoded)
+}
@@ -8,17 +8,25 @@ import (
 
 // QueryString converts properties to an HTTP query string
 func (p Properties) QueryString() string {
-	return p.QueryValues().Encode()
+	return p.QueryStringWithFormat(DefaultFormat)
+}
+
+// QueryStringWithFormat converts properties to an HTTP query string with parameters named according to a given format
+func (p Properties) QueryStringWithFormat(format Format) string {
+	return p.QueryValuesWithFormat(format).Encode()
 }
 
 // QueryValues converts properties to a set of HTTP query parameters
 func (p Properties) QueryValues() url.Values {
+	return p.QueryValuesWithFormat(DefaultFormat)
+}
+
+// QueryValuesWithFormat converts properties to a set of HTTP query parameters named according to a given format
+func (p Properties) QueryValuesWithFormat(format Format) url.Values {
 	values := url.Values{}
 
-	for service, props := range p {
-		for key, value := range props {
-			values.Set(GetPropertyName(service, key), value)
-		}
+	for name, entryValues := range p.EntriesWithFormat(format) {
+		values[name] = entryValues
 	}
 
 	return values
@@ -26,75 +34,174 @@ func (p Properties) QueryValues() url.Values {
 
 // FromQueryString parses properties from an HTTP query string
 func FromQueryString(query string) Properties {
+	return FromQueryStringWithFormat(query, DefaultFormat)
+}
+
+// FromQueryStringWithFormat parses properties of a given format from an HTTP query string
+func FromQueryStringWithFormat(query string, format Format) Properties {
 	parsedQuery, err := url.ParseQuery(query)
 	if err != nil {
 		return New()
 	}
 
-	return FromQueryValues(parsedQuery)
+	return FromQueryValuesWithFormat(parsedQuery, format)
 }
 
 // FromQueryValues parses properties from a parsed HTTP query string
 func FromQueryValues(values url.Values) Properties {
-	props := Properties{}
+	return FromQueryValuesWithFormat(values, DefaultFormat)
+}
+
+// FromQueryValuesWithFormat parses properties of a given format from a parsed HTTP query string
+func FromQueryValuesWithFormat(values url.Values, format Format) Properties {
+	return FromEntriesWithFormat(values, format).WithSource(SourceQuery)
+}
+
+// FromHeaders constructs properties from HTTP headers
+func FromHeaders(headers http.Header) Properties {
+	return FromHeadersWithFormat(headers, DefaultFormat)
+}
+
+// FromHeadersWithFormat constructs properties of a given format from HTTP headers
+func FromHeadersWithFormat(headers http.Header, format Format) Properties {
+	return FromEntriesWithFormat(headers, format).WithSource(SourceHeader)
+}
 
-	for name, values := range values {
-		serviceName, option, ok := ParsePropertyName(name)
-		if !ok {
-			continue
+// FromRequest constructs properties from HTTP headers and query string of the request.
+// Query string properties have a priority over HTTP headers, unless other precedence is set with WithPrecedence.
+func FromRequest(req *http.Request, opts ...RequestOption) Properties {
+	return FromRequestWithFormat(req, DefaultFormat, opts...)
+}
+
+// FromRequestWithFormat constructs properties of a given format from HTTP headers and query string of the request.
+// Query string properties have a priority over HTTP headers, unless other precedence is set with WithPrecedence.
+func FromRequestWithFormat(req *http.Request, format Format, opts ...RequestOption) Properties {
+	return fromRequest(req, format, FromEntriesWithFormat, opts)
+}
+
+// parses properties from a request with a given function
+func fromRequest(
+	req *http.Request,
+	format Format,
+	extract func(entries map[string][]string, format Format) Properties,
+	opts []RequestOption,
+) Properties {
+	options := requestOptions{precedence: []Source{SourceQuery, SourceHeader}}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	parsed := New()
+
+	for i := len(options.precedence) - 1; i >= 0; i-- {
+		switch options.precedence[i] {
+		case SourceHeader:
+			parsed.Merge(extract(req.Header, format).WithSource(SourceHeader))
+		case SourceQuery:
+			parsed.Merge(extract(req.URL.Query(), format).WithSource(SourceQuery))
 		}
+	}
+
+	result := options.limits.apply(options.policy.Apply(parsed), format).keepSignature(parsed)
 
-		if _, ok := props[serviceName]; !ok {
-			props[serviceName] = map[string]string{}
+	if options.onRejected != nil {
+		rejected := parsed.Filter(func(serviceName, prop string, values []string) bool {
+			value, ok := result.first(serviceName, prop)
+			return !ok || value != values[0]
+		})
+
+		if len(rejected.services) > 0 {
+			options.onRejected(req, rejected)
 		}
+	}
 
-		props[serviceName][option] = values[0]
+	if options.onApplied != nil && len(result.services) > 0 {
+		options.onApplied(req, result)
 	}
 
-	return props
+	return result
 }
 
-// FromHeaders constructs properties from HTTP headers
-func FromHeaders(headers http.Header) Properties {
-	props := New()
+// RequestOption configures how properties are parsed from a request
+type RequestOption func(options *requestOptions)
 
-	for name, values := range headers {
-		serviceName, option, ok := ParsePropertyName(name)
-		if !ok {
-			continue
-		}
+type requestOptions struct {
+	precedence []Source
+	policy     *Policy
+	limits     *Limits
+	onApplied  func(req *http.Request, props Properties)
+	onRejected func(req *http.Request, rejected Properties)
+}
 
-		if _, ok := props[serviceName]; !ok {
-			props[serviceName] = map[string]string{}
-		}
+// WithPrecedence sets the sources of properties in a request, from the highest priority to the lowest one,
+// e.g. WithPrecedence(SourceHeader, SourceQuery) for internal traffic. Only SourceHeader and SourceQuery are supported,
+// and the sources not listed are ignored: WithPrecedence(SourceHeader) parses the headers only.
+func WithPrecedence(sources ...Source) RequestOption {
+	return func(options *requestOptions) {
+		options.precedence = sources
+	}
+}
 
-		props[serviceName][option] = values[0]
+// WithPolicy applies a policy to the properties of a request, in addition to the one set with SetPolicy
+func WithPolicy(policy *Policy) RequestOption {
+	return func(options *requestOptions) {
+		options.policy = policy
 	}
+}
 
-	return props
+// WithLimits applies limits to the properties of a request, in addition to the ones set with SetLimits.
+// Unlike the latter, they are enforced on the parsed properties only, so that the dropped ones can be reported with OnRejected.
+func WithLimits(limits *Limits) RequestOption {
+	return func(options *requestOptions) {
+		options.limits = limits
+	}
 }
 
-// FromRequest constructs properties from HTTP headers and query string of the request.
-// Query string properties have a priority over HTTP headers.
-func FromRequest(req *http.Request) Properties {
-	fromHeaders := FromHeaders(req.Header)
-	fromQuery := FromQueryValues(req.URL.Query())
+// OnApplied sets a function called with the properties accepted from a request, e.g. for logging
+func OnApplied(fn func(req *http.Request, props Properties)) RequestOption {
+	return func(options *requestOptions) {
+		options.onApplied = fn
+	}
+}
 
-	return fromHeaders.Merge(fromQuery)
+// OnRejected sets a function called with the properties of a request rejected by WithPolicy or WithLimits, e.g. for logging
+func OnRejected(fn func(req *http.Request, rejected Properties)) RequestOption {
+	return func(options *requestOptions) {
+		options.onRejected = fn
+	}
 }
 
 // UrlBranchPlaceholder is a part of URL to be replaced with a branch name
 const UrlBranchPlaceholder = "$branch"
 
 // ReplaceUrlBranch replaces branch placeholder in URL with an actual branch name.
+// The branch name is normalized, but not validated, so it must come from a trusted source;
+// RoutingTransport and Resolve only accept the branch names consisting of [a-z0-9-] after normalization.
 func ReplaceUrlBranch(url, branch string) string {
 	if branch == "" || !strings.Contains(url, UrlBranchPlaceholder) {
 		return url
 	}
 
-	// try to normalize branch name for safe use in URLs
 	branch = strings.ToLower(strings.TrimSpace(branch))
 	branch = strings.ReplaceAll(branch, "_", "-")
 
 	return strings.ReplaceAll(url, UrlBranchPlaceholder, branch)
 }
+
+// normalizes a branch name for safe use in URLs, and checks that it consists of [a-z0-9-] only
+func normalizeBranch(branch string) (string, bool) {
+	branch = strings.ToLower(strings.TrimSpace(branch))
+	branch = strings.ReplaceAll(branch, "_", "-")
+
+	if branch == "" {
+		return "", false
+	}
+
+	for _, r := range branch {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return "", false
+		}
+	}
+
+	return branch, true
+}
@@ -18,9 +18,9 @@ func TestProperties_QueryString(t *testing.T) {
 }
 
 func TestFromQueryString(t *testing.T) {
-	require.Empty(t, FromQueryString(""))
-	require.Empty(t, FromQueryString("invalid;query"))
-	require.Empty(t, FromQueryString("city=Almaty&country=Kazakhstan"))
+	require.Empty(t, FromQueryString("").Services())
+	require.Empty(t, FromQueryString("invalid;query").Services())
+	require.Empty(t, FromQueryString("city=Almaty&country=Kazakhstan").Services())
 
 	require.Equal(
 		t,
@@ -64,6 +64,13 @@ func TestReplaceUrlBranch(t *testing.T) {
 		ReplaceUrlBranch("test-host-$branch", "\tFeature_123   "),
 		"branch name should be normalized",
 	)
+
+	require.Equal(
+		t,
+		"http://billing-release/1.2",
+		ReplaceUrlBranch("http://billing-$branch", "release/1.2"),
+		"branch name should not be validated",
+	)
 }
 
 func TestFromRequest(t *testing.T) {
@@ -81,3 +88,43 @@ func TestFromRequest(t *testing.T) {
 	require.Equal(t, "feature-123", props.Get("api", "branch", "main"))
 	require.Equal(t, "2.2", props.Get("billing", "version", "1.0"))
 }
+
+func TestFromRequest_Precedence(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/?x-service-billing-version=2.2&x-service-api-branch=feature-123", nil)
+	req.Header.Set("x-service-billing-version", "1.2")
+	req.Header.Set("x-service-billing-url", "http://billing")
+
+	props := FromRequest(req, WithPrecedence(SourceHeader, SourceQuery))
+	require.Equal(t, "1.2", props.Get("billing", "version", ""))
+	require.Equal(t, "feature-123", props.Get("api", "branch", ""))
+
+	props = FromRequest(req, WithPrecedence(SourceHeader))
+	require.Equal(t, New().Set("billing", "version", "1.2").Set("billing", "url", "http://billing"), props)
+
+	props = FromRequest(req, WithPrecedence(SourceQuery))
+	require.Equal(t, New().Set("billing", "version", "2.2").Set("api", "branch", "feature-123"), props)
+}
+
+func TestFromRequest_MultipleValues(t *testing.T) {
+	props := New()
+	props.Add("api", "tag", "one")
+	props.Add("api", "tag", "two")
+
+	req, _ := http.NewRequest("GET", "/?"+props.QueryString(), nil)
+	require.Equal(t, "x-service-api-tag=one&x-service-api-tag=two", req.URL.RawQuery)
+	require.Equal(t, props, FromQueryValues(req.URL.Query()))
+
+	props.InjectIntoHeaders(req.Header)
+	require.Equal(t, []string{"one", "two"}, req.Header.Values("x-service-api-tag"))
+	require.Equal(t, props, FromHeaders(req.Header))
+
+	props.InjectIntoHeaders(req.Header)
+	require.Equal(t, []string{"one", "two"}, req.Header.Values("x-service-api-tag"), "headers must be replaced, not appended")
+
+	req.URL.RawQuery = ""
+	firstFormat := Format{Prefix: NamePrefix, Separator: Separator, Duplicates: KeepFirst}
+	require.Equal(t, New().Set("api", "tag", "one"), FromRequestWithFormat(req, firstFormat))
+
+	lastFormat := Format{Prefix: NamePrefix, Separator: Separator, Duplicates: KeepLast}
+	require.Equal(t, New().Set("api", "tag", "two"), FromRequestWithFormat(req, lastFormat))
+}
@@ -0,0 +1,93 @@
+package servicectx
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Route describes a destination service of outgoing requests
+type Route struct {
+	// Service is a name of the service, e.g. "billing"
+	Service string
+	// BranchURL, if set, is a URL template with a branch placeholder, e.g. "http://billing-$branch:8080",
+	// used when the service branch is overridden with a "branch" property
+	BranchURL string
+}
+
+// RoutingTransport is an http.RoundTripper redirecting outgoing requests according to the "url" and "branch" properties
+// of their destination services, taken from the request context:
+//
+//	client := &http.Client{Transport: &servicectx.RoutingTransport{
+//		Routes: map[string]servicectx.Route{"billing.internal": {Service: "billing"}},
+//	}}
+//
+// With "x-service-billing-url: http://localhost:8080/v2", a request to "http://billing.internal/invoices"
+// is sent to "http://localhost:8080/v2/invoices": the scheme, the host, the port, and the path prefix are replaced.
+// A "url" property has a priority over a "branch" one.
+//
+// Redirecting requests to arbitrary hosts is dangerous, so the accepted properties should be restricted with SetPolicy.
+type RoutingTransport struct {
+	// Base is an underlying RoundTripper; http.DefaultTransport is used if nil.
+	// Use Transport to pass the properties to the destination as well.
+	Base http.RoundTripper
+	// Routes maps hosts of outgoing requests, with or without a port, to their services
+	Routes map[string]Route
+}
+
+// RoundTrip redirects a copy of the request if its destination is overridden, and executes it with the Base transport
+func (t *RoutingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if route, ok := t.route(req.URL); ok {
+		if target := routeTarget(FromContext(req.Context()), route); target != nil {
+			req = req.Clone(req.Context())
+			rewriteURL(req, target)
+		}
+	}
+
+	return baseTransport(t.Base).RoundTrip(req)
+}
+
+// finds a route by host and port, or by host only
+func (t *RoutingTransport) route(u *url.URL) (Route, bool) {
+	if route, ok := t.Routes[u.Host]; ok {
+		return route, true
+	}
+
+	route, ok := t.Routes[u.Hostname()]
+	return route, ok
+}
+
+// returns a URL overridden by the properties of a service, or nil if there is no valid override
+func routeTarget(props Properties, route Route) *url.URL {
+	rawURL := props.Get(route.Service, "url", "")
+	if rawURL == "" && route.BranchURL != "" {
+		if branch, ok := normalizeBranch(props.Get(route.Service, "branch", "")); ok {
+			rawURL = ReplaceUrlBranch(route.BranchURL, branch)
+		}
+	}
+
+	if rawURL == "" {
+		return nil
+	}
+
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil
+	}
+
+	return target
+}
+
+// replaces the scheme, the host, and the path prefix of a request with the ones of a target URL
+func rewriteURL(req *http.Request, target *url.URL) {
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	req.Host = target.Host
+
+	if prefix := strings.TrimSuffix(target.Path, "/"); prefix != "" {
+		req.URL.Path = prefix + "/" + strings.TrimPrefix(req.URL.Path, "/")
+		if req.URL.RawPath != "" {
+			req.URL.RawPath = strings.TrimSuffix(target.EscapedPath(), "/") + "/" + strings.TrimPrefix(req.URL.RawPath, "/")
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package servicectx
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// starts a server responding with its name and the request path
+func newNamedServer(t *testing.T, name string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, name+" "+r.URL.Path)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// executes a GET request and returns the response body
+func get(t *testing.T, client *http.Client, ctx context.Context, url string) string {
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestRoutingTransport(t *testing.T) {
+	main := newNamedServer(t, "main")
+	custom := newNamedServer(t, "custom")
+	mainHost := strings.TrimPrefix(main.URL, "http://")
+
+	client := &http.Client{Transport: &RoutingTransport{
+		Routes: map[string]Route{mainHost: {Service: "billing"}},
+	}}
+
+	// no overrides
+	require.Equal(t, "main /invoices", get(t, client, context.Background(), main.URL+"/invoices"))
+
+	// the url is overridden
+	ctx := New().Set("billing", "url", custom.URL).InjectIntoContext(context.Background())
+	require.Equal(t, "custom /invoices", get(t, client, ctx, main.URL+"/invoices"))
+
+	// the url is overridden along with a path prefix
+	ctx = New().Set("billing", "url", custom.URL+"/v2/").InjectIntoContext(context.Background())
+	require.Equal(t, "custom /v2/invoices", get(t, client, ctx, main.URL+"/invoices"))
+
+	// the properties of other services don't matter
+	ctx = New().Set("api", "url", custom.URL).InjectIntoContext(context.Background())
+	require.Equal(t, "main /invoices", get(t, client, ctx, main.URL+"/invoices"))
+
+	// an invalid url is ignored
+	ctx = New().Set("billing", "url", "localhost").InjectIntoContext(context.Background())
+	require.Equal(t, "main /invoices", get(t, client, ctx, main.URL+"/invoices"))
+}
+
+func TestRoutingTransport_Branch(t *testing.T) {
+	main := newNamedServer(t, "main")
+	custom := newNamedServer(t, "custom")
+	customPort := custom.URL[strings.LastIndex(custom.URL, ":")+1:]
+
+	client := &http.Client{Transport: &RoutingTransport{
+		Routes: map[string]Route{
+			"127.0.0.1": {Service: "billing", BranchURL: "http://$branch:" + customPort},
+		},
+	}}
+
+	ctx := New().Set("billing", "branch", "localhost").InjectIntoContext(context.Background())
+	require.Equal(t, "custom /invoices", get(t, client, ctx, main.URL+"/invoices"))
+
+	// a url has a priority over a branch
+	ctx = New().Set("billing", "branch", "localhost").Set("billing", "url", main.URL).InjectIntoContext(context.Background())
+	require.Equal(t, "main /invoices", get(t, client, ctx, custom.URL+"/invoices"))
+
+	// a branch can't change the host
+	ctx = New().Set("billing", "branch", "evil.com/x").InjectIntoContext(context.Background())
+	require.Equal(t, "main /invoices", get(t, client, ctx, main.URL+"/invoices"))
+}
+
+func TestRoutingTransport_WithTransport(t *testing.T) {
+	var received http.Header
+	custom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	}))
+	defer custom.Close()
+
+	client := &http.Client{Transport: &RoutingTransport{
+		Base:   &Transport{},
+		Routes: map[string]Route{"billing.internal": {Service: "billing"}},
+	}}
+
+	ctx := New().Set("billing", "url", custom.URL).InjectIntoContext(context.Background())
+	get(t, client, ctx, "http://billing.internal/invoices")
+
+	require.Equal(t, custom.URL, received.Get("x-service-billing-url"))
+}
@@ -0,0 +1,217 @@
+package servicectx
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PropertySpec declares an overridable property of a service
+type PropertySpec struct {
+	// Name of the property, e.g. "timeout"
+	Name string
+	// Type of the property value, e.g. reflect.TypeOf(time.Duration(0)); values are not type-checked if nil
+	Type reflect.Type
+	// Default is a raw default value, used when the property is missing
+	Default string
+	// Allowed is a list of allowed values; any value is allowed if empty
+	Allowed []string
+	// Pattern is a regular expression a value must match, if set
+	Pattern *regexp.Regexp
+	// Description of the property for documentation purposes
+	Description string
+}
+
+// Schema declares overridable properties of services, along with their types, defaults, and constraints
+type Schema struct {
+	specs map[string]map[string]PropertySpec
+}
+
+// NewSchema constructs an empty schema
+func NewSchema() *Schema {
+	return &Schema{specs: map[string]map[string]PropertySpec{}}
+}
+
+// Declare adds property specs for a given service. A spec with the same name replaces the existing one.
+func (s *Schema) Declare(serviceName string, specs ...PropertySpec) *Schema {
+	serviceName = sanitizeServiceName(serviceName)
+	if _, ok := s.specs[serviceName]; !ok {
+		s.specs[serviceName] = map[string]PropertySpec{}
+	}
+
+	for _, spec := range specs {
+		s.specs[serviceName][spec.Name] = spec
+	}
+
+	return s
+}
+
+// Spec returns a spec of a given property, and whether it was declared
+func (s *Schema) Spec(serviceName, prop string) (PropertySpec, bool) {
+	spec, ok := s.specs[sanitizeServiceName(serviceName)][prop]
+	return spec, ok
+}
+
+// Specs returns specs of all properties declared for a given service, sorted by name
+func (s *Schema) Specs(serviceName string) []PropertySpec {
+	var result []PropertySpec
+	for _, spec := range s.specs[sanitizeServiceName(serviceName)] {
+		result = append(result, spec)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// Get returns a property value for a given service, or its declared default value if the property is missing
+func (s *Schema) Get(p Properties, serviceName, prop string) string {
+	spec, _ := s.Spec(serviceName, prop)
+
+	return p.Get(serviceName, prop, spec.Default)
+}
+
+// GetDeclared returns a property value for a given service converted to type T.
+// If the property is missing, empty, or can't be converted to T, its declared default value is used instead.
+func GetDeclared[T any](s *Schema, p Properties, serviceName, prop string) T {
+	spec, _ := s.Spec(serviceName, prop)
+	defaultValue, _, _ := Lookup[T](New().Set(serviceName, prop, spec.Default), serviceName, prop)
+
+	return Get(p, serviceName, prop, defaultValue)
+}
+
+// ValidationError describes a property that doesn't conform to a schema
+type ValidationError struct {
+	// Service name
+	Service string
+	// Property name
+	Property string
+	// Value is a raw property value; multiple values are joined with commas
+	Value string
+	// Reason why the property is invalid
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("servicectx: invalid property %q of service %q with value %q: %s", e.Property, e.Service, e.Value, e.Reason)
+}
+
+// ValidationErrors is a list of all properties that don't conform to a schema
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks properties against a schema, and returns ValidationErrors listing unknown and invalid properties.
+// Only the services declared in the schema are validated:
+// properties of other services are expected to be passed downstream, and are ignored.
+// The properties of AllServices are validated against the specs of every declared service they apply to.
+func (p Properties) Validate(schema *Schema) error {
+	var result ValidationErrors
+
+	for _, serviceName := range p.Services() {
+		values := p.services[serviceName]
+		if serviceName == AllServices {
+			result = append(result, p.validateAll(schema)...)
+			continue
+		}
+
+		specs, ok := schema.specs[serviceName]
+		if !ok {
+			continue
+		}
+
+		for prop := range values {
+			propValues, _ := p.values(serviceName, prop)
+			value := strings.Join(propValues, ",")
+
+			spec, ok := specs[prop]
+			if !ok {
+				result = append(result, &ValidationError{serviceName, prop, value, "unknown property"})
+				continue
+			}
+
+			if reason := spec.validate(propValues); reason != "" {
+				result = append(result, &ValidationError{serviceName, prop, value, reason})
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Service != result[j].Service {
+			return result[i].Service < result[j].Service
+		}
+
+		return result[i].Property < result[j].Property
+	})
+
+	return result
+}
+
+// validates the properties of AllServices against the specs of the declared services having no such properties of their own
+func (p Properties) validateAll(schema *Schema) ValidationErrors {
+	var result ValidationErrors
+
+	for _, prop := range sortedKeys(p.services[AllServices]) {
+		propValues, _ := p.values(AllServices, prop)
+
+		for _, serviceName := range sortedKeys(schema.specs) {
+			spec, ok := schema.specs[serviceName][prop]
+			if !ok || p.HasProperty(serviceName, prop) {
+				continue
+			}
+
+			if reason := spec.validate(propValues); reason != "" {
+				reason = fmt.Sprintf("%s for service %q", reason, serviceName)
+				result = append(result, &ValidationError{AllServices, prop, strings.Join(propValues, ","), reason})
+			}
+		}
+	}
+
+	return result
+}
+
+// returns a reason why the values don't conform to the spec, or an empty string if they do
+func (spec PropertySpec) validate(values []string) string {
+	if spec.Type != nil {
+		if _, err := parseValues(spec.Type, values); err != nil {
+			return fmt.Sprintf("expected %s: %v", spec.Type, err)
+		}
+	}
+
+	for _, value := range values {
+		if len(spec.Allowed) > 0 && !containsString(spec.Allowed, value) {
+			return fmt.Sprintf("expected one of %s", strings.Join(spec.Allowed, ", "))
+		}
+
+		if spec.Pattern != nil && !spec.Pattern.MatchString(value) {
+			return fmt.Sprintf("expected to match %s", spec.Pattern)
+		}
+	}
+
+	return ""
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,107 @@
+package servicectx
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func testSchema() *Schema {
+	return NewSchema().
+		Declare(
+			"billing",
+			PropertySpec{
+				Name:        "timeout",
+				Type:        reflect.TypeOf(time.Duration(0)),
+				Default:     "3s",
+				Description: "request timeout",
+			},
+			PropertySpec{
+				Name:    "log-level",
+				Default: "info",
+				Allowed: []string{"debug", "info", "error"},
+			},
+			PropertySpec{
+				Name:    "branch",
+				Default: "main",
+				Pattern: regexp.MustCompile(`^[a-z0-9-]+$`),
+			},
+		)
+}
+
+func TestSchema_Get(t *testing.T) {
+	schema := testSchema()
+	props := New().Set("billing", "log-level", "debug").Set("billing", "timeout", "forever")
+
+	require.Equal(t, "debug", schema.Get(props, "billing", "log-level"))
+	require.Equal(t, "main", schema.Get(props, "billing", "branch"))
+	require.Equal(t, "", schema.Get(props, "billing", "unknown"))
+
+	require.Equal(t, 3*time.Second, GetDeclared[time.Duration](schema, props, "billing", "timeout"))
+	props.Set("billing", "timeout", "5s")
+	require.Equal(t, 5*time.Second, GetDeclared[time.Duration](schema, props, "billing", "timeout"))
+	require.Equal(t, 0, GetDeclared[int](schema, props, "billing", "unknown"))
+
+	spec, ok := schema.Spec("billing", "timeout")
+	require.True(t, ok)
+	require.Equal(t, "request timeout", spec.Description)
+
+	specs := schema.Specs("billing")
+	require.Len(t, specs, 3)
+	require.Equal(t, "branch", specs[0].Name)
+}
+
+func TestProperties_Validate(t *testing.T) {
+	schema := testSchema()
+
+	require.NoError(t, New().Validate(schema))
+	require.NoError(
+		t,
+		New().
+			Set("billing", "timeout", "5s").
+			Set("billing", "log-level", "error").
+			Set("api", "anything", "goes").
+			Validate(schema),
+	)
+
+	err := New().
+		Set("billing", "timeout", "3 seconds").
+		Set("billing", "log-level", "verbose").
+		Set("billing", "color", "red").
+		Add("billing", "branch", "feature-123").
+		Add("billing", "branch", "Feature_123").
+		Validate(schema)
+
+	var validationErrors ValidationErrors
+	require.True(t, errors.As(err, &validationErrors))
+	require.Len(t, validationErrors, 4)
+
+	require.Equal(t, &ValidationError{"billing", "branch", "feature-123,Feature_123", "expected to match ^[a-z0-9-]+$"}, validationErrors[0])
+	require.Equal(t, &ValidationError{"billing", "color", "red", "unknown property"}, validationErrors[1])
+	require.Equal(t, &ValidationError{"billing", "log-level", "verbose", "expected one of debug, info, error"}, validationErrors[2])
+	require.Equal(t, "timeout", validationErrors[3].Property)
+	require.Contains(t, validationErrors[3].Reason, "expected time.Duration")
+
+	require.Contains(t, err.Error(), `servicectx: invalid property "color" of service "billing" with value "red": unknown property; `)
+}
+
+func TestProperties_Validate_AllServices(t *testing.T) {
+	schema := testSchema()
+
+	require.NoError(t, New().Set("all", "log-level", "debug").Set("all", "anything", "goes").Validate(schema))
+	require.NoError(
+		t,
+		New().Set("all", "log-level", "verbose").Set("billing", "log-level", "info").Validate(schema),
+		"a property of all services must not be validated for a service having its own one",
+	)
+
+	err := New().Set("all", "log-level", "verbose").Validate(schema)
+	require.Equal(
+		t,
+		ValidationErrors{{"all", "log-level", "verbose", `expected one of debug, info, error for service "billing"`}},
+		err,
+	)
+}
@@ -0,0 +1,164 @@
+package servicectx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SealedPrefix marks encrypted property values: "sealed:{KEY_ID}:{BASE64_NONCE_AND_CIPHERTEXT}"
+const SealedPrefix = "sealed:"
+
+// ErrInvalidSealedValue is returned when an encrypted value is malformed or can't be decrypted
+var ErrInvalidSealedValue = errors.New("servicectx: invalid sealed value")
+
+// IsSealed checks if a property value is encrypted
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, SealedPrefix)
+}
+
+// Seal encrypts a property value with the active key using AES-256-GCM.
+// The value is bound to the service and property name, so it can't be moved to another property.
+func (r *KeyRing) Seal(serviceName, prop, value string) (string, error) {
+	id, key, err := r.activeKey()
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), sealingData(serviceName, prop))
+
+	return SealedPrefix + id + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Unseal decrypts a property value encrypted with Seal
+func (r *KeyRing) Unseal(serviceName, prop, value string) (string, error) {
+	if !IsSealed(value) {
+		return "", ErrInvalidSealedValue
+	}
+
+	value = strings.TrimPrefix(value, SealedPrefix)
+	i := strings.LastIndex(value, ":")
+	if i < 0 {
+		return "", ErrInvalidSealedValue
+	}
+
+	key, err := r.key(value[:i])
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidSealedValue
+	}
+
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	opened, err := aead.Open(nil, nonce, sealed, sealingData(serviceName, prop))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSealedValue, err)
+	}
+
+	return string(opened), nil
+}
+
+// creates AES-256-GCM cipher with a key derived from a key ring secret of any length
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("servicectx sealing"))
+
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// additional authenticated data binding a sealed value to a property
+func sealingData(serviceName, prop string) []byte {
+	return []byte(strings.ToLower(serviceName) + "\x00" + strings.ToLower(prop))
+}
+
+// Sealing configures encryption of sensitive property values
+type Sealing struct {
+	// Keys is a key ring shared by all services
+	Keys *KeyRing
+	// Rules lists the properties to be encrypted in outgoing carriers:
+	// InjectIntoHeaders, HeaderMap, QueryValues, Entries, and OpenTelemetry/OpenTracing baggage.
+	// Encrypted values are decrypted by all parse functions, regardless of the rules.
+	// The same rules should be used by all services, so that the decrypted values are encrypted again when passed downstream.
+	Rules []Rule
+}
+
+// the sealing configuration set with SetSealing
+var sealing struct {
+	sync.RWMutex
+	value *Sealing
+}
+
+// SetSealing enables encryption of sensitive property values. A nil value (the default) disables it.
+func SetSealing(s *Sealing) {
+	sealing.Lock()
+	defer sealing.Unlock()
+
+	sealing.value = s
+}
+
+// returns the sealing configuration set with SetSealing
+func currentSealing() *Sealing {
+	sealing.RLock()
+	defer sealing.RUnlock()
+
+	return sealing.value
+}
+
+// encrypts an outgoing value if it matches the sealing rules;
+// if the value can't be encrypted, an empty string and false are returned, so that the value is never leaked
+func (s *Sealing) seal(serviceName, prop, value string) (string, bool) {
+	if s == nil || IsSealed(value) || !matchesAny(s.Rules, serviceName, prop) {
+		return value, true
+	}
+
+	sealed, err := s.Keys.Seal(serviceName, prop, value)
+	if err != nil {
+		return "", false
+	}
+
+	return sealed, true
+}
+
+// decrypts an incoming value if it's encrypted; returns false if it can't be decrypted
+func (s *Sealing) unseal(serviceName, prop, value string) (string, bool) {
+	if s == nil || !IsSealed(value) {
+		return value, true
+	}
+
+	unsealed, err := s.Keys.Unseal(serviceName, prop, value)
+	if err != nil {
+		return "", false
+	}
+
+	return unsealed, true
+}
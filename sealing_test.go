@@ -0,0 +1,77 @@
+package servicectx
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestKeyRing_SealUnseal(t *testing.T) {
+	ring := NewKeyRing("v1", []byte("secret"))
+
+	sealed, err := ring.Seal("api", "token", "test-account-token")
+	require.NoError(t, err)
+	require.True(t, IsSealed(sealed))
+	require.True(t, strings.HasPrefix(sealed, "sealed:v1:"))
+	require.NotContains(t, sealed, "test-account-token")
+
+	anotherSealed, err := ring.Seal("api", "token", "test-account-token")
+	require.NoError(t, err)
+	require.NotEqual(t, sealed, anotherSealed, "a random nonce must be used")
+
+	unsealed, err := ring.Unseal("api", "token", sealed)
+	require.NoError(t, err)
+	require.Equal(t, "test-account-token", unsealed)
+
+	_, err = ring.Unseal("billing", "token", sealed)
+	require.ErrorIs(t, err, ErrInvalidSealedValue, "a sealed value must be bound to a property")
+
+	_, err = ring.Unseal("api", "token", "test-account-token")
+	require.ErrorIs(t, err, ErrInvalidSealedValue)
+	_, err = ring.Unseal("api", "token", "sealed:v1:???")
+	require.ErrorIs(t, err, ErrInvalidSealedValue)
+	_, err = ring.Unseal("api", "token", "sealed:v1:AAAA")
+	require.ErrorIs(t, err, ErrInvalidSealedValue)
+
+	_, err = NewKeyRing("v2", []byte("secret")).Unseal("api", "token", sealed)
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestSetSealing(t *testing.T) {
+	ring := NewKeyRing("v1", []byte("secret"))
+	SetSealing(&Sealing{Keys: ring, Rules: []Rule{{Property: "token"}}})
+	defer SetSealing(nil)
+
+	props := New().Set("api", "token", "test-account-token").Set("api", "branch", "feature-123")
+
+	req, _ := http.NewRequest("GET", "/?"+props.QueryString(), nil)
+	require.True(t, IsSealed(req.URL.Query().Get("x-service-api-token")))
+	require.Equal(t, "feature-123", req.URL.Query().Get("x-service-api-branch"))
+	require.True(t, IsSealed(props.HeaderMap()["x-service-api-token"]))
+
+	props.InjectIntoHeaders(req.Header)
+	require.True(t, IsSealed(req.Header.Get("x-service-api-token")))
+
+	require.Equal(t, props, FromHeaders(req.Header))
+	require.Equal(t, props, FromRequest(req))
+
+	// a value that can't be decrypted is dropped
+	req.Header.Set("x-service-api-token", "sealed:v2:AAAA")
+	require.Equal(t, New().Set("api", "branch", "feature-123"), FromHeaders(req.Header))
+
+	// without a key ring, encrypted values are passed as is
+	SetSealing(nil)
+	require.True(t, IsSealed(FromQueryValues(req.URL.Query()).Get("api", "token", "")))
+}
+
+func TestSetSealing_Signing(t *testing.T) {
+	ring := NewKeyRing("v1", []byte("secret"))
+	SetSealing(&Sealing{Keys: ring, Rules: []Rule{{Property: "token"}}})
+	SetSigning(&Signing{Keys: ring, Sign: true, Verify: true})
+	defer SetSealing(nil)
+	defer SetSigning(nil)
+
+	props := New().Set("api", "token", "test-account-token")
+	require.True(t, props.Equal(FromEntries(props.Entries())), "a signature must be verified against decrypted values")
+}
@@ -0,0 +1,73 @@
+package servicectx
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AllServices is a reserved service name for the properties applying to every service, e.g. "x-service-all-log-level".
+// Get and other getters fall back to it when a service has no property of its own.
+const AllServices = "all"
+
+// dashed service names, registered with RegisterServiceName
+var serviceNames = struct {
+	sync.RWMutex
+	names map[string]struct{}
+}{
+	names: map[string]struct{}{},
+}
+
+// RegisterServiceName declares service names containing dashes (e.g. "user-profile"),
+// so that they are not collapsed into "userprofile", and are recognized in property names
+// like "x-service-user-profile-branch" (as service "user-profile" and option "branch").
+//
+// Without the registration, dashed service names can still be sent unambiguously
+// by escaping the separator with a double one: "x-service-user--profile-branch".
+func RegisterServiceName(names ...string) {
+	serviceNames.Lock()
+	defer serviceNames.Unlock()
+
+	for _, name := range names {
+		if name != "" {
+			serviceNames.names[name] = struct{}{}
+		}
+	}
+}
+
+// isRegisteredServiceName checks if a service name was declared with RegisterServiceName
+func isRegisteredServiceName(name string) bool {
+	serviceNames.RLock()
+	defer serviceNames.RUnlock()
+
+	_, ok := serviceNames.names[name]
+	return ok
+}
+
+// registeredServiceNames returns the names declared with RegisterServiceName, longest first
+func registeredServiceNames() []string {
+	serviceNames.RLock()
+	defer serviceNames.RUnlock()
+
+	result := make([]string, 0, len(serviceNames.names))
+	for name := range serviceNames.names {
+		result = append(result, name)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return len(result[i]) > len(result[j])
+	})
+
+	return result
+}
+
+// removes dashes from service names,
+// so that "my-service" and "myservice" are treated equally,
+// unless a dashed name was declared with RegisterServiceName.
+func sanitizeServiceName(name string) string {
+	if !strings.Contains(name, "-") || isRegisteredServiceName(name) {
+		return name
+	}
+
+	return strings.ReplaceAll(name, "-", "")
+}
@@ -0,0 +1,110 @@
+package servicectx
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRegisterServiceName(t *testing.T) {
+	RegisterServiceName("user-profile")
+	defer func() {
+		serviceNames.Lock()
+		delete(serviceNames.names, "user-profile")
+		serviceNames.Unlock()
+	}()
+
+	props := New()
+	props.Set("user-profile", "branch", "feature-123")
+	props.Set("userprofile", "branch", "main")
+	require.Equal(t, "feature-123", props.Get("user-profile", "branch", ""))
+	require.Equal(t, "main", props.Get("userprofile", "branch", ""))
+
+	require.Equal(
+		t,
+		map[string]string{
+			"x-service-user--profile-branch": "feature-123",
+			"x-service-userprofile-branch":   "main",
+		},
+		props.HeaderMap(),
+		"dashes in service names must be escaped",
+	)
+	require.Equal(t, props, FromQueryValues(props.QueryValues()))
+
+	serviceName, option, ok := ParsePropertyName("x-service-user-profile-branch")
+	require.True(t, ok)
+	require.Equal(t, "user-profile", serviceName, "a registered service name must be recognized without escaping")
+	require.Equal(t, "branch", option)
+
+	serviceName, option, ok = ParsePropertyName("x-service-user-branch")
+	require.True(t, ok)
+	require.Equal(t, "user", serviceName)
+	require.Equal(t, "branch", option)
+}
+
+func TestParsePropertyName_Escaping(t *testing.T) {
+	serviceName, option, ok := ParsePropertyName("x-service-user--profile-branch")
+	require.True(t, ok)
+	require.Equal(t, "user-profile", serviceName)
+	require.Equal(t, "branch", option)
+
+	serviceName, option, ok = ParsePropertyName("x-service-a--b--c-timeout-ms")
+	require.True(t, ok)
+	require.Equal(t, "a-b-c", serviceName)
+	require.Equal(t, "timeout-ms", option)
+	require.Equal(t, "x-service-a--b--c-timeout-ms", GetPropertyName(serviceName, option))
+
+	_, _, ok = ParsePropertyName("x-service-user--profile")
+	require.False(t, ok, "an escaped separator must not be treated as a separator")
+
+	serviceName, _, ok = Format{Prefix: "kt", Separator: "_"}.ParsePropertyName("kt_my__svc_branch")
+	require.True(t, ok)
+	require.Equal(t, "my_svc", serviceName)
+
+	_, _, ok = Format{Prefix: "kt"}.ParsePropertyName("ktapibranch")
+	require.False(t, ok, "a format without separator must not be parsed")
+
+	// an unregistered dashed service is kept intact, and doesn't collide with "userprofile"
+	props := FromQueryString("x-service-user--profile-branch=feature-123&x-service-userprofile-branch=master")
+	require.Equal(t, "feature-123", props.Get("user-profile", "branch", ""))
+	require.Equal(t, "master", props.Get("userprofile", "branch", ""))
+	require.Equal(t, []string{"user-profile", "userprofile"}, props.Services())
+	require.Equal(
+		t,
+		map[string]string{"x-service-user--profile-branch": "feature-123", "x-service-userprofile-branch": "master"},
+		props.HeaderMap(),
+	)
+
+	props.Set("user-profile", "branch", "feature-456").Delete("userprofile", "branch")
+	require.Equal(t, map[string]string{"x-service-user--profile-branch": "feature-456"}, props.HeaderMap())
+}
+
+func TestAllServices(t *testing.T) {
+	props := FromQueryString("x-service-all-log-level=debug&x-service-all-timeout=5s&x-service-api-log-level=error&x-service-all-url=http://all&x-service-all-url.hops=2")
+
+	// a service-specific property has a preference
+	require.Equal(t, "error", props.Get("api", "log-level", "info"))
+	// otherwise, the property of all services is used
+	require.Equal(t, "debug", props.Get("billing", "log-level", "info"))
+	require.Equal(t, []string{"debug"}, props.GetAll("billing", "log-level"))
+	require.Equal(t, 5*time.Second, props.GetDuration("api", "timeout", time.Second))
+	require.Equal(t, "default", props.Get("api", "branch", "default"))
+
+	// metadata is resolved along with its property
+	hops, ok := props.Hops("api", "url")
+	require.True(t, ok)
+	require.Equal(t, 1, hops)
+
+	require.False(t, props.HasProperty("billing", "log-level"))
+	require.Equal(t, "x-service-all-log-level=debug", New().Set(AllServices, "log-level", "debug").QueryString())
+}
+
+func TestAllServices_Bind(t *testing.T) {
+	var cfg struct {
+		LogLevel string `servicectx:"log-level,required"`
+	}
+
+	props := New().Set(AllServices, "log-level", "debug")
+	require.NoError(t, props.Bind("billing", &cfg))
+	require.Equal(t, "debug", cfg.LogLevel)
+}
@@ -0,0 +1,296 @@
+package servicectx
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnsigned is returned when properties have no signature
+	ErrUnsigned = errors.New("servicectx: properties are not signed")
+	// ErrInvalidSignature is returned when a signature doesn't match the properties
+	ErrInvalidSignature = errors.New("servicectx: invalid properties signature")
+	// ErrUnknownKey is returned when a signature or an encryption key ID is not in the key ring
+	ErrUnknownKey = errors.New("servicectx: unknown key")
+	// ErrExpiredSignature is returned when a signature is older than Signing.MaxAge
+	ErrExpiredSignature = errors.New("servicectx: expired properties signature")
+)
+
+// a metadata attribute with no service and property, holding a verified signature of received properties
+const signatureAttribute = "signature"
+
+// KeyRing holds shared secret keys by their IDs, with one of them being active (used for signing and encryption).
+// To rotate the keys, add a new key to all services, then make it active, and finally remove the old one.
+type KeyRing struct {
+	mu       sync.RWMutex
+	keys     map[string][]byte
+	activeID string
+}
+
+// NewKeyRing constructs a new key ring with an active key
+func NewKeyRing(activeID string, activeKey []byte) *KeyRing {
+	ring := &KeyRing{keys: map[string][]byte{}}
+
+	return ring.Add(activeID, activeKey).Activate(activeID)
+}
+
+// Add adds a key to the key ring. The key can be used for verification, but it is not active until Activate is called.
+func (r *KeyRing) Add(id string, key []byte) *KeyRing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[id] = key
+
+	return r
+}
+
+// Activate makes a key with a given ID active. It must be added to the key ring first.
+func (r *KeyRing) Activate(id string) *KeyRing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.activeID = id
+
+	return r
+}
+
+// Remove removes a key from the key ring
+func (r *KeyRing) Remove(id string) *KeyRing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.keys, id)
+
+	return r
+}
+
+// returns an active key and its ID
+func (r *KeyRing) activeKey() (string, []byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[r.activeID]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %q", ErrUnknownKey, r.activeID)
+	}
+
+	return r.activeID, key, nil
+}
+
+// returns a key by its ID
+func (r *KeyRing) key(id string) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	return key, nil
+}
+
+// Sign computes an HMAC-SHA256 signature of properties with the active key.
+// The signature has a form of "{KEY_ID}:{UNIX_TIME}:{BASE64_HMAC}", the signing time being signed too.
+// The hop counters (see SetHops) are not signed, as every service decrements them, but the maximum hops are.
+func (r *KeyRing) Sign(props Properties) (string, error) {
+	return r.signAt(props, time.Now())
+}
+
+// signs properties as if it was done at a given time
+func (r *KeyRing) signAt(props Properties, now time.Time) (string, error) {
+	id, key, err := r.activeKey()
+	if err != nil {
+		return "", err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	return id + ":" + timestamp + ":" + base64.RawURLEncoding.EncodeToString(computeHMAC(key, timestamp, props)), nil
+}
+
+// Verify checks a signature of properties produced with Sign.
+// Empty properties don't need a signature.
+func (r *KeyRing) Verify(props Properties, signature string) error {
+	_, err := r.verify(props, signature)
+
+	return err
+}
+
+// checks a signature of properties, and returns the time it was signed at
+func (r *KeyRing) verify(props Properties, signature string) (time.Time, error) {
+	if signature == "" {
+		if len(props.Services()) == 0 {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, ErrUnsigned
+	}
+
+	i := strings.LastIndex(signature, ":")
+	if i < 0 {
+		return time.Time{}, ErrInvalidSignature
+	}
+
+	j := strings.LastIndex(signature[:i], ":")
+	if j < 0 {
+		return time.Time{}, ErrInvalidSignature
+	}
+
+	key, err := r.key(signature[:j])
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	timestamp := signature[j+1 : i]
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidSignature
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature[i+1:])
+	if err != nil || !hmac.Equal(mac, computeHMAC(key, timestamp, props)) {
+		return time.Time{}, ErrInvalidSignature
+	}
+
+	return time.Unix(signedAt, 0), nil
+}
+
+// VerifyEntries checks a signature of properties of a given format in a map of property names and their values,
+// such as http.Header or url.Values.
+// It can be used to reject a request with unsigned or tampered properties, rather than silently dropping them.
+func (r *KeyRing) VerifyEntries(entries map[string][]string, format Format) error {
+	return r.Verify(parseEntries(entries, format), signatureFromEntries(entries, format))
+}
+
+// computes an HMAC of properties in a canonical form that doesn't depend on a carrier or a key format:
+// a signing time, followed by a sorted list of length-prefixed lowercase service names, property names, and their values.
+// The hop counters are skipped, as they change on every hop; they are capped by the signed maximum hops instead.
+func computeHMAC(key []byte, timestamp string, props Properties) []byte {
+	mac := hmac.New(sha256.New, key)
+	writeLengthPrefixed(mac, timestamp)
+
+	props.eachEntry(func(serviceName, prop string, values []string) {
+		if _, attribute, _ := splitMetaName(prop); attribute == HopsAttribute {
+			return
+		}
+
+		writeLengthPrefixed(mac, strings.ToLower(serviceName))
+		writeLengthPrefixed(mac, strings.ToLower(prop))
+		fmt.Fprintf(mac, "%d:", len(values))
+		for _, value := range values {
+			writeLengthPrefixed(mac, value)
+		}
+	})
+
+	return mac.Sum(nil)
+}
+
+func writeLengthPrefixed(w io.Writer, value string) {
+	fmt.Fprintf(w, "%d:%s", len(value), value)
+}
+
+// finds a signature in a map of property names and their values
+func signatureFromEntries(entries map[string][]string, format Format) string {
+	name := format.SignatureName()
+
+	for key, values := range entries {
+		if len(values) > 0 && (key == name || !format.CaseSensitive && strings.EqualFold(key, name)) {
+			return values[0]
+		}
+	}
+
+	return ""
+}
+
+// Signing configures signing of outgoing properties and verification of incoming ones.
+//
+// A service which verifies the properties, but doesn't sign them, forwards the received signature,
+// as long as it forwards the same properties: adding, changing, or dropping any of them
+// (e.g. when they run out of hops or are consumed) invalidates the signature for the next verifying service.
+//
+// There is no protection against replays other than MaxAge: a signed set of properties
+// can be sent again by anyone who has seen it, until it expires.
+type Signing struct {
+	// Keys is a key ring shared by all services
+	Keys *KeyRing
+	// Sign adds a signature to outgoing properties
+	// in InjectIntoHeaders, QueryValues, Entries, and OpenTelemetry/OpenTracing baggage
+	Sign bool
+	// Verify drops incoming properties with a missing or invalid signature
+	// in FromRequest, FromHeaders, FromQueryValues, FromEntries, and OpenTelemetry/OpenTracing extractors.
+	// It should be disabled on a trusted edge service, which accepts properties from the outside world.
+	Verify bool
+	// MaxAge makes Verify drop the properties signed longer ago. Zero means signatures don't expire.
+	MaxAge time.Duration
+}
+
+// verifies a signature of received properties, checking its age
+func (s *Signing) verify(props Properties, signature string, now time.Time) error {
+	signedAt, err := s.Keys.verify(props, signature)
+	if err != nil {
+		return err
+	}
+
+	if s.MaxAge > 0 && !signedAt.IsZero() && now.Sub(signedAt) > s.MaxAge {
+		return ErrExpiredSignature
+	}
+
+	return nil
+}
+
+// copies a verified signature of received properties from other properties, to be forwarded (see Signing).
+// The receiver is modified and returned for chaining.
+func (p Properties) keepSignature(other Properties) Properties {
+	if signature, ok := other.hidden(signatureAttribute, "", ""); ok && len(p.Services()) > 0 {
+		p.setHidden(signatureAttribute, "", "", signature)
+	}
+
+	return p
+}
+
+// returns a signature of outgoing properties: a new one if signing is enabled,
+// otherwise the received one, if the properties are forwarded unchanged
+func (s *Signing) signature(props Properties, received string) string {
+	if s.Sign {
+		signature, _ := s.Keys.Sign(props)
+		return signature
+	}
+
+	if received != "" && s.Keys.Verify(props, received) == nil {
+		return received
+	}
+
+	return ""
+}
+
+// the signing configuration set with SetSigning
+var signing struct {
+	sync.RWMutex
+	value *Signing
+}
+
+// SetSigning enables signing of outgoing properties and/or verification of incoming ones.
+// A nil value (the default) disables both.
+func SetSigning(s *Signing) {
+	signing.Lock()
+	defer signing.Unlock()
+
+	signing.value = s
+}
+
+// returns the signing configuration set with SetSigning
+func currentSigning() *Signing {
+	signing.RLock()
+	defer signing.RUnlock()
+
+	return signing.value
+}
@@ -0,0 +1,149 @@
+package servicectx
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestKeyRing_SignVerify(t *testing.T) {
+	ring := NewKeyRing("v1", []byte("secret-1"))
+	props := New().Set("api", "branch", "feature-123").Add("billing", "tag", "a").Add("billing", "tag", "b")
+
+	signature, err := ring.Sign(props)
+	require.NoError(t, err)
+	require.Regexp(t, `^v1:[0-9]+:[A-Za-z0-9_-]+$`, signature)
+	require.NoError(t, ring.Verify(props, signature))
+	require.NoError(t, ring.Verify(New(), ""), "empty properties don't need a signature")
+
+	require.ErrorIs(t, ring.Verify(props, ""), ErrUnsigned)
+	require.ErrorIs(t, ring.Verify(props, "invalid"), ErrInvalidSignature)
+	require.ErrorIs(t, ring.Verify(props, "v1:???"), ErrInvalidSignature)
+	require.ErrorIs(t, ring.Verify(props, "v2:"+signature[3:]), ErrUnknownKey)
+
+	tampered := New().Merge(props).Set("api", "branch", "main")
+	require.ErrorIs(t, ring.Verify(tampered, signature), ErrInvalidSignature)
+
+	reordered := New().Set("api", "branch", "feature-123").Add("billing", "tag", "b").Add("billing", "tag", "a")
+	require.ErrorIs(t, ring.Verify(reordered, signature), ErrInvalidSignature, "the order of values must be signed")
+
+	// key rotation: the old signature stays valid until the old key is removed
+	ring.Add("v2", []byte("secret-2")).Activate("v2")
+	newSignature, err := ring.Sign(props)
+	require.NoError(t, err)
+	require.Regexp(t, `^v2:`, newSignature)
+	require.NoError(t, ring.Verify(props, signature))
+	require.NoError(t, ring.Verify(props, newSignature))
+
+	ring.Remove("v1")
+	require.ErrorIs(t, ring.Verify(props, signature), ErrUnknownKey)
+
+	ring.Activate("v3")
+	_, err = ring.Sign(props)
+	require.True(t, errors.Is(err, ErrUnknownKey))
+}
+
+func TestSetSigning(t *testing.T) {
+	ring := NewKeyRing("v1", []byte("secret"))
+	props := New().Set("api", "branch", "feature-123")
+
+	// a trusted edge signs the properties
+	SetSigning(&Signing{Keys: ring, Sign: true})
+	req, _ := http.NewRequest("GET", "/?"+props.QueryString(), nil)
+	props.InjectIntoHeaders(req.Header)
+	require.NotEmpty(t, req.Header.Get("x-service-signature"))
+	require.NotEmpty(t, req.URL.Query().Get("x-service-signature"))
+	require.NoError(t, ring.VerifyEntries(req.Header, DefaultFormat))
+
+	// the next service verifies them
+	SetSigning(&Signing{Keys: ring, Verify: true})
+	defer SetSigning(nil)
+
+	require.True(t, props.Equal(FromRequest(req)))
+	require.True(t, props.Equal(FromHeaders(req.Header)))
+	require.True(t, props.Equal(FromQueryValues(req.URL.Query())))
+
+	// a forged header invalidates the signature
+	req.Header.Set("x-service-billing-url", "http://evil")
+	require.ErrorIs(t, ring.VerifyEntries(req.Header, DefaultFormat), ErrInvalidSignature)
+	require.Empty(t, FromHeaders(req.Header).Services())
+	require.True(t, props.Equal(FromRequest(req)), "properties from a correctly signed query string must be kept")
+
+	// unsigned properties are dropped
+	require.Empty(t, FromQueryString("x-service-api-branch=main").Services())
+	require.Empty(t, FromQueryString("").Services())
+}
+
+func TestSetSigning_MaxAge(t *testing.T) {
+	ring := NewKeyRing("v1", []byte("secret"))
+	props := New().Set("api", "branch", "feature-123")
+
+	SetSigning(&Signing{Keys: ring, Verify: true, MaxAge: time.Minute})
+	defer SetSigning(nil)
+
+	signature, err := ring.Sign(props)
+	require.NoError(t, err)
+	require.True(t, props.Equal(FromEntries(map[string][]string{"x-service-api-branch": {"feature-123"}, "x-service-signature": {signature}})))
+
+	// a replayed old signature is rejected
+	signature, err = ring.signAt(props, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, ring.Verify(props, signature), "KeyRing.Verify doesn't check the age")
+	require.Empty(t, FromEntries(map[string][]string{"x-service-api-branch": {"feature-123"}, "x-service-signature": {signature}}).Services())
+	require.ErrorIs(t, currentSigning().verify(props, signature, time.Now()), ErrExpiredSignature)
+
+	// the signing time can't be changed
+	forged := "v1:" + "9" + signature[3:]
+	require.ErrorIs(t, ring.Verify(props, forged), ErrInvalidSignature)
+}
+
+func TestSetSigning_ThreeHops(t *testing.T) {
+	ring := NewKeyRing("v1", []byte("secret"))
+
+	// the edge signs the properties
+	SetSigning(&Signing{Keys: ring, Sign: true})
+	defer SetSigning(nil)
+
+	headers := http.Header{}
+	New().
+		Set("api", "branch", "feature-123").
+		SetHops("api", "branch", 3).
+		InjectIntoHeaders(headers)
+
+	// the next service verifies them without signing, and forwards them with decremented hops
+	SetSigning(&Signing{Keys: ring, Verify: true})
+	received := FromHeaders(headers)
+	require.Equal(t, "feature-123", received.Get("api", "branch", ""))
+
+	forwarded := http.Header{}
+	received.InjectIntoHeaders(forwarded)
+	require.Equal(t, "2", forwarded.Get("x-service-api-branch.hops"))
+	require.Equal(t, headers.Get("x-service-signature"), forwarded.Get("x-service-signature"))
+
+	// the signature is forwarded from the request context as well
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header = headers
+	fromContext := http.Header{}
+	InjectIntoHeadersFromContext(InjectIntoContextFromRequest(req.Context(), req), fromContext)
+	require.Equal(t, headers.Get("x-service-signature"), fromContext.Get("x-service-signature"))
+
+	// the last service verifies them too
+	last := FromHeaders(forwarded)
+	require.Equal(t, "feature-123", last.Get("api", "branch", ""))
+	hops, _ := last.Hops("api", "branch")
+	require.Equal(t, 1, hops)
+
+	// the maximum hops are signed, so they can't be raised on the way
+	tampered := forwarded.Clone()
+	tampered.Set("x-service-api-branch.hops", "10")
+	tampered.Set("x-service-api-branch.maxhops", "10")
+	require.Empty(t, FromHeaders(tampered).Services())
+
+	// modified properties are forwarded without the received signature, and dropped by the next verifying service
+	modified := http.Header{}
+	received.Clone().Set("api", "branch", "main").InjectIntoHeaders(modified)
+	require.Empty(t, modified.Get("x-service-signature"))
+	require.Empty(t, FromHeaders(modified).Services())
+}
@@ -0,0 +1,99 @@
+package servicectx
+
+import "sync"
+
+// Source is an origin of a property value
+type Source string
+
+const (
+	// SourceHeader means a property was parsed from HTTP headers
+	SourceHeader Source = "header"
+	// SourceQuery means a property was parsed from a query string
+	SourceQuery Source = "query"
+	// SourceBaggage means a property was parsed from OpenTelemetry baggage
+	SourceBaggage Source = "baggage"
+	// SourceSpan means a property was parsed from OpenTracing span baggage
+	SourceSpan Source = "span"
+	// SourceContext means a property was retrieved from Go context
+	SourceContext Source = "context"
+	// SourceProgrammatic means a property was set in application code
+	SourceProgrammatic Source = "programmatic"
+)
+
+// SourceAttribute is a reserved metadata attribute name, so that a source can't be sent as "x-service-api-url.source".
+// The sources are never propagated.
+const SourceAttribute = "source"
+
+// whether source tracking is enabled with SetSourceTracking
+var sourceTracking struct {
+	sync.RWMutex
+	value bool
+}
+
+// SetSourceTracking enables recording the sources of parsed properties, which can then be queried with Properties.Source and Properties.Sources.
+// It's meant for debugging and is disabled by default.
+func SetSourceTracking(enabled bool) {
+	sourceTracking.Lock()
+	defer sourceTracking.Unlock()
+
+	sourceTracking.value = enabled
+}
+
+// checks if source tracking is enabled with SetSourceTracking
+func isSourceTrackingEnabled() bool {
+	sourceTracking.RLock()
+	defer sourceTracking.RUnlock()
+
+	return sourceTracking.value
+}
+
+// Source returns the source of a property value, and whether it is known.
+// It's unknown if there is no such property, or if source tracking was disabled with SetSourceTracking when the property was set.
+func (p Properties) Source(serviceName, prop string) (Source, bool) {
+	sources := p.Sources(serviceName, prop)
+	if len(sources) == 0 {
+		return "", false
+	}
+
+	return sources[len(sources)-1], true
+}
+
+// Sources returns all known sources of a property in the order they were merged, the last one being the source of its value,
+// e.g. a header value overridden by a query string value gives [header, query].
+func (p Properties) Sources(serviceName, prop string) []Source {
+	return p.history(p.resolveService(serviceName, prop), prop)
+}
+
+// returns the sources of a property of a service with an exact name. With source tracking enabled,
+// a property with no recorded source is considered to be set in application code.
+func (p Properties) history(serviceName, prop string) []Source {
+	if !p.HasProperty(serviceName, prop) {
+		return nil
+	}
+
+	if sources, ok := p.sources[propertyKey{serviceName, prop}]; ok {
+		return append([]Source(nil), sources...)
+	}
+
+	if isSourceTrackingEnabled() {
+		return []Source{SourceProgrammatic}
+	}
+
+	return nil
+}
+
+// WithSource records a source of the properties having no source yet, if source tracking is enabled with SetSourceTracking.
+// It's used by the functions parsing properties from carriers. The receiver is modified and returned for chaining.
+func (p Properties) WithSource(source Source) Properties {
+	if !isSourceTrackingEnabled() {
+		return p
+	}
+
+	p.each(func(serviceName, prop string, _ []string) {
+		if _, ok := p.sources[propertyKey{serviceName, prop}]; !ok {
+			p.sources[propertyKey{serviceName, prop}] = []Source{source}
+		}
+	})
+
+	return p
+}
@@ -0,0 +1,86 @@
+package servicectx
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestProperties_Source(t *testing.T) {
+	SetSourceTracking(true)
+	defer SetSourceTracking(false)
+
+	req := &http.Request{
+		Header: http.Header{},
+		URL:    &url.URL{RawQuery: "x-service-api-url=http://query"},
+	}
+	req.Header.Set("x-service-api-url", "http://header")
+	req.Header.Set("x-service-api-branch", "main")
+
+	props := FromRequest(req)
+
+	source, ok := props.Source("api", "url")
+	require.True(t, ok)
+	require.Equal(t, SourceQuery, source)
+	require.Equal(t, []Source{SourceHeader, SourceQuery}, props.Sources("api", "url"), "url came from the query string, overriding the header")
+
+	source, ok = props.Source("api", "branch")
+	require.True(t, ok)
+	require.Equal(t, SourceHeader, source)
+
+	_, ok = props.Source("api", "version")
+	require.False(t, ok)
+
+	// the source is not visible as a property, and doesn't affect equality
+	require.Equal(t, Values{"url": "http://query", "branch": "main"}, props.GetByService("api"))
+	require.True(t, FromQueryString("x-service-api-url=u").Equal(New().Set("api", "url", "u")))
+}
+
+func TestProperties_Source_Programmatic(t *testing.T) {
+	SetSourceTracking(true)
+	defer SetSourceTracking(false)
+
+	props := New().Set("api", "url", "http://api")
+	source, ok := props.Source("api", "url")
+	require.True(t, ok)
+	require.Equal(t, SourceProgrammatic, source)
+
+	props = FromQueryString("x-service-api-url=http://query").Merge(New().Set("api", "url", "http://api"))
+	source, ok = props.Source("api", "url")
+	require.True(t, ok)
+	require.Equal(t, SourceProgrammatic, source, "a value set in application code must override the source")
+	require.Equal(t, []Source{SourceQuery, SourceProgrammatic}, props.Sources("api", "url"))
+}
+
+func TestProperties_Source_Disabled(t *testing.T) {
+	props := FromQueryString("x-service-api-url=http://query")
+
+	require.Equal(t, New().Set("api", "url", "http://query"), props)
+
+	source, ok := props.Source("api", "url")
+	require.False(t, ok, "the source must be unknown if source tracking is disabled")
+	require.Equal(t, Source(""), source)
+	require.Nil(t, props.Sources("api", "url"))
+
+	// a source recorded with tracking enabled stays known
+	SetSourceTracking(true)
+	props = FromQueryString("x-service-api-url=http://query")
+	SetSourceTracking(false)
+	source, ok = props.Source("api", "url")
+	require.True(t, ok)
+	require.Equal(t, SourceQuery, source)
+}
+
+func TestProperties_Source_NotPropagated(t *testing.T) {
+	SetSourceTracking(true)
+	defer SetSourceTracking(false)
+
+	props := FromQueryString("x-service-api-url=http://query")
+	require.Equal(t, "x-service-api-url=http%3A%2F%2Fquery", props.QueryString())
+
+	// a source sent by a client is ignored
+	props = FromQueryString("x-service-api-url=http://query&x-service-api-url.source=programmatic")
+	source, _ := props.Source("api", "url")
+	require.Equal(t, SourceQuery, source)
+}
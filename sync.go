@@ -0,0 +1,236 @@
+package servicectx
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SyncProperties is a concurrency-safe container of properties, which can be shared between goroutines.
+// It has the same methods as Properties.
+type SyncProperties struct {
+	mu    sync.RWMutex
+	props Properties
+}
+
+// NewSync constructs a new concurrency-safe properties instance
+func NewSync() *SyncProperties {
+	return &SyncProperties{props: New()}
+}
+
+// NewSyncFrom constructs a new concurrency-safe properties instance holding a copy of given properties
+func NewSyncFrom(props Properties) *SyncProperties {
+	return &SyncProperties{props: props.Clone()}
+}
+
+// Snapshot returns a copy of properties, which can be used without synchronization
+func (s *SyncProperties) Snapshot() Properties {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.Clone()
+}
+
+// HasService checks if there are options for a given service
+func (s *SyncProperties) HasService(serviceName string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.HasService(serviceName)
+}
+
+// HasProperty checks if a given property exists for a service
+func (s *SyncProperties) HasProperty(serviceName, option string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.HasProperty(serviceName, option)
+}
+
+// GetByService returns a copy of all options for a given service
+func (s *SyncProperties) GetByService(serviceName string) Values {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	values := s.props.GetByService(serviceName)
+	if values == nil {
+		return nil
+	}
+
+	result := make(Values, len(values))
+	for key, value := range values {
+		result[key] = value
+	}
+
+	return result
+}
+
+// Get returns an property value for a given service
+func (s *SyncProperties) Get(serviceName, prop, defaultValue string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.Get(serviceName, prop, defaultValue)
+}
+
+// Lookup returns a property value for a given service and whether the property exists
+func (s *SyncProperties) Lookup(serviceName, prop string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.Lookup(serviceName, prop)
+}
+
+// GetAll returns all values of a property for a given service, or nil if there is no such property
+func (s *SyncProperties) GetAll(serviceName, prop string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.GetAll(serviceName, prop)
+}
+
+// GetInt returns a property value for a given service as an integer
+func (s *SyncProperties) GetInt(serviceName, prop string, defaultValue int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.GetInt(serviceName, prop, defaultValue)
+}
+
+// GetDuration returns a property value for a given service as time.Duration
+func (s *SyncProperties) GetDuration(serviceName, prop string, defaultValue time.Duration) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.GetDuration(serviceName, prop, defaultValue)
+}
+
+// GetBool returns a property value for a given service as boolean
+func (s *SyncProperties) GetBool(serviceName, prop string, defaultValue bool) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.GetBool(serviceName, prop, defaultValue)
+}
+
+// LookupInt returns a property value for a given service as an integer, and whether a non-empty property was found
+func (s *SyncProperties) LookupInt(serviceName, prop string) (int, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.LookupInt(serviceName, prop)
+}
+
+// LookupDuration returns a property value for a given service as time.Duration, and whether a non-empty property was found
+func (s *SyncProperties) LookupDuration(serviceName, prop string) (time.Duration, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.LookupDuration(serviceName, prop)
+}
+
+// LookupBool returns a property value for a given service as boolean, and whether a non-empty property was found
+func (s *SyncProperties) LookupBool(serviceName, prop string) (bool, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.LookupBool(serviceName, prop)
+}
+
+// Set sets a property value for a given service
+func (s *SyncProperties) Set(serviceName, prop, value string) *SyncProperties {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.props.Set(serviceName, prop, value)
+
+	return s
+}
+
+// Add appends a value to a property of a given service, keeping its existing values
+func (s *SyncProperties) Add(serviceName, prop, value string) *SyncProperties {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.props.Add(serviceName, prop, value)
+
+	return s
+}
+
+// Delete removes a property along with its metadata. The receiver is modified and returned for chaining.
+func (s *SyncProperties) Delete(serviceName, prop string) *SyncProperties {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.props.Delete(serviceName, prop)
+
+	return s
+}
+
+// DeleteService removes all properties of a service. The receiver is modified and returned for chaining.
+func (s *SyncProperties) DeleteService(serviceName string) *SyncProperties {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.props.DeleteService(serviceName)
+
+	return s
+}
+
+// Services returns the names of services having properties, in alphabetical order
+func (s *SyncProperties) Services() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.Services()
+}
+
+// Merge merges other properties into this instance. The receiver is modified and returned for chaining.
+func (s *SyncProperties) Merge(other Properties) *SyncProperties {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.props.Merge(other)
+
+	return s
+}
+
+// Forwardable returns a copy of properties to be passed to downstream services
+func (s *SyncProperties) Forwardable() Properties {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.Forwardable()
+}
+
+// HeaderMap returns options as a map of HTTP headers
+func (s *SyncProperties) HeaderMap() map[string]string {
+	return s.HeaderMapWithFormat(DefaultFormat)
+}
+
+// HeaderMapWithFormat returns options as a map of HTTP headers named according to a given format
+func (s *SyncProperties) HeaderMapWithFormat(format Format) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.props.HeaderMapWithFormat(format)
+}
+
+// InjectIntoHeaders adds property headers to http.Header
+func (s *SyncProperties) InjectIntoHeaders(headers http.Header) {
+	s.InjectIntoHeadersWithFormat(headers, DefaultFormat)
+}
+
+// InjectIntoHeadersWithFormat adds property headers named according to a given format to http.Header
+func (s *SyncProperties) InjectIntoHeadersWithFormat(headers http.Header, format Format) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	s.props.InjectIntoHeadersWithFormat(headers, format)
+}
+
+// InjectIntoContext adds a snapshot of properties to the context
+func (s *SyncProperties) InjectIntoContext(ctx context.Context) context.Context {
+	return withProperties(ctx, s.Snapshot())
+}
@@ -0,0 +1,119 @@
+package servicectx
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSyncProperties(t *testing.T) {
+	props := NewSyncFrom(New().Set("api", "timeout", "3s"))
+
+	require.True(t, props.HasService("api"))
+	require.True(t, props.HasProperty("api", "timeout"))
+	require.Equal(t, 3*time.Second, props.GetDuration("api", "timeout", time.Second))
+
+	props.Set("api", "version", "2").Add("api", "tag", "one").Add("api", "tag", "two")
+	props.Merge(New().Set("billing", "enabled", "true"))
+
+	require.Equal(t, 2, props.GetInt("api", "version", 1))
+	require.Equal(t, []string{"one", "two"}, props.GetAll("api", "tag"))
+	require.True(t, props.GetBool("billing", "enabled", false))
+	require.Equal(t, "true", props.Get("billing", "enabled", ""))
+
+	values := props.GetByService("billing")
+	values["enabled"] = "false"
+	require.Equal(t, "true", props.Get("billing", "enabled", ""), "a copy of values must be returned")
+	require.Nil(t, props.GetByService("unknown"))
+
+	require.Equal(t, "true", props.HeaderMap()["x-service-billing-enabled"])
+
+	headers := http.Header{}
+	props.InjectIntoHeaders(headers)
+	require.Equal(t, "2", headers.Get("x-service-api-version"))
+
+	snapshot := props.Snapshot()
+	props.Set("api", "version", "3")
+	require.Equal(t, "2", snapshot.Get("api", "version", ""))
+	require.Equal(t, "3", FromContext(props.InjectIntoContext(context.Background())).Get("api", "version", ""))
+}
+
+func TestSyncProperties_Concurrent(t *testing.T) {
+	props := NewSync()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			props.Set("api", "index-"+strconv.Itoa(i), strconv.Itoa(i))
+			props.Merge(New().Set("billing", "index", strconv.Itoa(i)))
+			props.Get("billing", "index", "")
+			props.InjectIntoHeaders(http.Header{})
+		}(i)
+	}
+
+	wg.Wait()
+	require.Len(t, props.GetByService("api"), 10)
+}
+
+func BenchmarkProperties_Get(b *testing.B) {
+	props := New().Set("api", "version", "2")
+
+	for i := 0; i < b.N; i++ {
+		props.Get("api", "version", "")
+	}
+}
+
+func BenchmarkSyncProperties_Get(b *testing.B) {
+	props := NewSync().Set("api", "version", "2")
+
+	for i := 0; i < b.N; i++ {
+		props.Get("api", "version", "")
+	}
+}
+
+func BenchmarkProperties_GetSetParallel(b *testing.B) {
+	var mu sync.Mutex
+	props := New().Set("api", "version", "2")
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			mu.Lock()
+			if i%10 == 0 {
+				props.Set("api", "version", "3")
+			} else {
+				props.Get("api", "version", "")
+			}
+			mu.Unlock()
+		}
+	})
+}
+
+func BenchmarkSyncProperties_GetSetParallel(b *testing.B) {
+	props := NewSync().Set("api", "version", "2")
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if i%10 == 0 {
+				props.Set("api", "version", "3")
+			} else {
+				props.Get("api", "version", "")
+			}
+		}
+	})
+}
+
+func TestSyncProperties_Delete(t *testing.T) {
+	props := NewSync().Set("a", "url", "http://a").Set("a", "branch", "main").Set("b", "url", "http://b")
+
+	props.Delete("a", "url").DeleteService("b")
+
+	require.Equal(t, []string{"a"}, props.Services())
+	require.Equal(t, New().Set("a", "branch", "main"), props.Snapshot())
+}
@@ -0,0 +1,201 @@
+package servicectx
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Carrier defines how Transport passes properties to downstream services
+type Carrier int
+
+const (
+	// HeaderCarrier passes properties in HTTP headers, like InjectIntoHeaders
+	HeaderCarrier Carrier = iota
+	// QueryCarrier passes properties in a query string, like QueryValues
+	QueryCarrier
+	// BaggageCarrier passes properties in a W3C "baggage" header, for the services using OpenTelemetry propagation.
+	// Multiple values are encoded with JoinValues, and escaped as OpenTelemetry baggage values.
+	BaggageCarrier
+)
+
+// baggageHeader is a W3C baggage HTTP header
+const baggageHeader = "baggage"
+
+// Transport is an http.RoundTripper passing properties from the request context (see InjectIntoContext and Middleware)
+// to downstream services:
+//
+//	client := &http.Client{Transport: &servicectx.Transport{}}
+//	client.Do(req.WithContext(ctx))
+type Transport struct {
+	// Base is an underlying RoundTripper; http.DefaultTransport is used if nil
+	Base http.RoundTripper
+	// Carrier defines how properties are passed; HTTP headers are used by default
+	Carrier Carrier
+	// Format is a property name format; DefaultFormat is used if nil
+	Format *Format
+	// Filter, if set, decides which services' properties are passed to the destination of a request
+	Filter func(req *http.Request, serviceName string) bool
+}
+
+// RoundTrip passes properties from the request context to a copy of the request, and executes it with the Base transport.
+// The entries of the format already present in the request (e.g. the baggage members propagated from an incoming request)
+// are replaced, so that the properties excluded by Filter or not forwardable don't leak.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	props := outgoingFromContext(req.Context())
+	if t.Filter != nil {
+		props = props.Filter(func(serviceName, prop string, values []string) bool {
+			return t.Filter(req, serviceName)
+		})
+	}
+
+	format := t.format()
+	if len(props.Services()) > 0 || hasEntries(req, format) {
+		req = req.Clone(req.Context())
+		t.inject(req, props, format)
+	}
+
+	return baseTransport(t.Base).RoundTrip(req)
+}
+
+// FilterByHost returns a Transport filter passing only the properties of given services to each host,
+// e.g. {"billing.internal": {"billing", "payments"}}, along with the properties of AllServices.
+// No properties are passed to the hosts not listed, such as external APIs.
+func FilterByHost(services map[string][]string) func(req *http.Request, serviceName string) bool {
+	return func(req *http.Request, serviceName string) bool {
+		allowedServices, listed := services[req.URL.Hostname()]
+		if serviceName == AllServices {
+			return listed
+		}
+
+		for _, allowed := range allowedServices {
+			if allowed == serviceName || sanitizeServiceName(allowed) == serviceName {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// adds properties to a request according to the carrier, removing the existing entries of the format
+func (t *Transport) inject(req *http.Request, props Properties, format Format) {
+	for name := range req.Header {
+		if isFormatEntry(name, format) {
+			req.Header.Del(name)
+		}
+	}
+
+	query := req.URL.Query()
+	modifiedQuery := false
+	for name := range query {
+		if isFormatEntry(name, format) {
+			query.Del(name)
+			modifiedQuery = true
+		}
+	}
+
+	var baggageEntries map[string][]string
+
+	switch t.Carrier {
+	case QueryCarrier:
+		for name, values := range props.QueryValuesWithFormat(format) {
+			query[name] = values
+			modifiedQuery = true
+		}
+	case BaggageCarrier:
+		baggageEntries = props.EntriesWithFormat(format)
+	default:
+		props.InjectIntoHeadersWithFormat(req.Header, format)
+	}
+
+	if modifiedQuery {
+		req.URL.RawQuery = query.Encode()
+	}
+
+	if len(baggageEntries) > 0 || len(req.Header.Values(baggageHeader)) > 0 {
+		injectIntoBaggageHeader(req.Header, baggageEntries, format)
+	}
+}
+
+// returns the Format or DefaultFormat
+func (t *Transport) format() Format {
+	if t.Format != nil {
+		return *t.Format
+	}
+
+	return DefaultFormat
+}
+
+// checks if a request already has entries of a format in its headers, query string, or baggage
+func hasEntries(req *http.Request, format Format) bool {
+	for name := range req.Header {
+		if isFormatEntry(name, format) {
+			return true
+		}
+	}
+
+	for name := range req.URL.Query() {
+		if isFormatEntry(name, format) {
+			return true
+		}
+	}
+
+	for _, header := range req.Header.Values(baggageHeader) {
+		for _, member := range strings.Split(header, ",") {
+			if isFormatEntry(baggageMemberKey(member), format) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checks if an entry name is a property or a signature of a format
+func isFormatEntry(name string, format Format) bool {
+	if _, _, ok := format.ParsePropertyName(name); ok {
+		return true
+	}
+
+	return name == format.SignatureName() || !format.CaseSensitive && strings.EqualFold(name, format.SignatureName())
+}
+
+// returns a key of a W3C baggage member
+func baggageMemberKey(member string) string {
+	return strings.TrimSpace(strings.SplitN(member, "=", 2)[0])
+}
+
+// returns a base transport, or http.DefaultTransport if it's nil
+func baseTransport(base http.RoundTripper) http.RoundTripper {
+	if base != nil {
+		return base
+	}
+
+	return http.DefaultTransport
+}
+
+// adds entries to a W3C baggage header, replacing the existing members of the format
+func injectIntoBaggageHeader(headers http.Header, entries map[string][]string, format Format) {
+	var members []string
+
+	for _, header := range headers.Values(baggageHeader) {
+		for _, member := range strings.Split(header, ",") {
+			member = strings.TrimSpace(member)
+			if member != "" && !isFormatEntry(baggageMemberKey(member), format) {
+				members = append(members, member)
+			}
+		}
+	}
+
+	// the values are escaped like OpenTelemetry does, as the receiving side unescapes them
+	for _, name := range sortedKeys(entries) {
+		members = append(members, name+"="+url.QueryEscape(JoinValues(entries[name])))
+	}
+
+	if len(members) > 0 {
+		headers.Set(baggageHeader, strings.Join(members, ","))
+	} else {
+		headers.Del(baggageHeader)
+	}
+}
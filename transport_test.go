@@ -0,0 +1,123 @@
+package servicectx
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// starts a server recording the last received request
+func newRecordingServer(t *testing.T) (*httptest.Server, *http.Request) {
+	received := &http.Request{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = *r
+	}))
+	t.Cleanup(server.Close)
+
+	return server, received
+}
+
+func TestTransport(t *testing.T) {
+	server, received := newRecordingServer(t)
+	client := &http.Client{Transport: &Transport{}}
+
+	ctx := New().Set("api", "branch", "main").Add("api", "tag", "one").Add("api", "tag", "two").InjectIntoContext(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	_, err := client.Do(req)
+	require.NoError(t, err)
+
+	require.Equal(t, "main", received.Header.Get("x-service-api-branch"))
+	require.Equal(t, []string{"one", "two"}, received.Header.Values("x-service-api-tag"))
+	require.Empty(t, req.Header, "the original request must not be modified")
+}
+
+func TestTransport_QueryCarrier(t *testing.T) {
+	server, received := newRecordingServer(t)
+	client := &http.Client{Transport: &Transport{Carrier: QueryCarrier}}
+
+	ctx := New().Set("api", "branch", "main").InjectIntoContext(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/?page=2", nil)
+	_, err := client.Do(req)
+	require.NoError(t, err)
+
+	require.Equal(t, "page=2&x-service-api-branch=main", received.URL.RawQuery)
+	require.Empty(t, received.Header.Get("x-service-api-branch"))
+}
+
+func TestTransport_BaggageCarrier(t *testing.T) {
+	server, received := newRecordingServer(t)
+	client := &http.Client{Transport: &Transport{Carrier: BaggageCarrier}}
+
+	ctx := New().Set("api", "branch", "main").Add("api", "tag", "one").Add("api", "tag", "t w,o").InjectIntoContext(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	req.Header.Set("baggage", "userId=42, x-service-api-branch=old;prop=1")
+	_, err := client.Do(req)
+	require.NoError(t, err)
+
+	require.Equal(t, "userId=42,x-service-api-branch=main,x-service-api-tag=one%7Ct%2520w%252Co", received.Header.Get("baggage"))
+}
+
+func TestTransport_Filter(t *testing.T) {
+	server, received := newRecordingServer(t)
+	client := &http.Client{Transport: &Transport{
+		Format: &Format{Prefix: "x-kt", Separator: "-"},
+		Filter: FilterByHost(map[string][]string{"127.0.0.1": {"billing"}}),
+	}}
+
+	ctx := New().Set("api", "branch", "main").Set("billing", "branch", "hotfix").InjectIntoContext(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	_, err := client.Do(req)
+	require.NoError(t, err)
+
+	require.Equal(t, "hotfix", received.Header.Get("x-kt-billing-branch"))
+	require.Empty(t, received.Header.Get("x-kt-api-branch"))
+}
+
+func TestTransport_FilterStaleEntries(t *testing.T) {
+	server, received := newRecordingServer(t)
+	client := &http.Client{Transport: &Transport{
+		Carrier: BaggageCarrier,
+		Filter:  FilterByHost(map[string][]string{"127.0.0.1": {"billing"}}),
+	}}
+
+	ctx := New().
+		Set("api", "branch", "main").
+		Set("billing", "branch", "hotfix").
+		Set(AllServices, "log-level", "debug").
+		InjectIntoContext(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/?page=2&x-service-api-url=http://evil", nil)
+	// the entries propagated from an incoming request
+	req.Header.Set("baggage", "userId=42,x-service-api-branch=main,x-service-signature=v1:1:abc")
+	req.Header.Set("x-service-api-branch", "main")
+	_, err := client.Do(req)
+	require.NoError(t, err)
+
+	require.Equal(t, "userId=42,x-service-all-log-level=debug,x-service-billing-branch=hotfix", received.Header.Get("baggage"))
+	require.Empty(t, received.Header.Get("x-service-api-branch"))
+	require.Equal(t, "page=2", received.URL.RawQuery)
+
+	// nothing is passed to the hosts not listed, not even the properties of all services
+	filter := FilterByHost(map[string][]string{"billing.internal": {"billing"}})
+	require.False(t, filter(req, AllServices))
+	require.False(t, filter(req, "billing"))
+}
+
+func TestTransport_PassThrough(t *testing.T) {
+	SetMode(PassThrough)
+	defer resetMode()
+
+	server, received := newRecordingServer(t)
+	client := &http.Client{Transport: &Transport{}}
+
+	incoming := httptest.NewRequest("GET", "/", nil)
+	incoming.Header.Set("x-service-api-branch", "main")
+	ctx := InjectIntoContextFromRequest(context.Background(), incoming)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	_, err := client.Do(req)
+	require.NoError(t, err)
+
+	require.Equal(t, "main", received.Header.Get("x-service-api-branch"))
+}
@@ -0,0 +1,200 @@
+package servicectx
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// parses a raw property value into a value of a registered type
+type parserFunc func(value string) (interface{}, error)
+
+// parsers registered with RegisterParser, by the type they produce
+var parsers = struct {
+	sync.RWMutex
+	byType map[reflect.Type]parserFunc
+}{
+	byType: map[reflect.Type]parserFunc{},
+}
+
+func init() {
+	RegisterParser(func(value string) (string, error) { return value, nil })
+	RegisterParser(func(value string) ([]byte, error) { return []byte(value), nil })
+	RegisterParser(strconv.ParseBool)
+	RegisterParser(strconv.Atoi)
+	RegisterParser(parseInt[int8](8))
+	RegisterParser(parseInt[int16](16))
+	RegisterParser(parseInt[int32](32))
+	RegisterParser(parseInt[int64](64))
+	RegisterParser(parseUint[uint](0))
+	RegisterParser(parseUint[uint8](8))
+	RegisterParser(parseUint[uint16](16))
+	RegisterParser(parseUint[uint32](32))
+	RegisterParser(parseUint[uint64](64))
+	RegisterParser(parseFloat[float32](32))
+	RegisterParser(parseFloat[float64](64))
+	RegisterParser(time.ParseDuration)
+	RegisterParser(func(value string) (time.Time, error) { return time.Parse(time.RFC3339, value) })
+	RegisterParser(url.Parse)
+}
+
+// RegisterParser registers a function that converts property values to type T, for use with Get.
+// A parser registered for an already known type replaces the existing one.
+//
+// Built-in parsers support strings, booleans, integers, floats, time.Duration, time.Time (RFC 3339), and *url.URL.
+// Types with no registered parser are parsed with encoding.TextUnmarshaler if they implement it.
+// Otherwise, slices are parsed from comma-separated lists (and multiple values of a property), and other types as JSON.
+func RegisterParser[T any](parse func(value string) (T, error)) {
+	parsers.Lock()
+	defer parsers.Unlock()
+
+	parsers.byType[typeOf[T]()] = func(value string) (interface{}, error) {
+		return parse(value)
+	}
+}
+
+// Get returns a property value for a given service converted to type T.
+// If the property is missing, empty, or can't be converted to T, a default value is returned.
+func Get[T any](p Properties, serviceName, prop string, defaultValue T) T {
+	value, found, err := Lookup[T](p, serviceName, prop)
+	if !found || err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// Lookup returns a property value for a given service converted to type T, and whether the property was found.
+// An empty value is treated as missing. If the value can't be converted to T, a *ParseError is returned.
+func Lookup[T any](p Properties, serviceName, prop string) (value T, found bool, err error) {
+	values := p.GetAll(serviceName, prop)
+	if len(values) == 0 || values[0] == "" {
+		return value, false, nil
+	}
+
+	typ := typeOf[T]()
+	parsed, err := parseValues(typ, values)
+	if err != nil {
+		return value, true, &ParseError{
+			Service:  serviceName,
+			Property: prop,
+			Value:    strings.Join(values, ","),
+			Type:     typ.String(),
+			Err:      err,
+		}
+	}
+
+	reflect.ValueOf(&value).Elem().Set(convertValue(parsed, typ))
+
+	return value, true, nil
+}
+
+// ParseError describes a property value that can't be converted to the requested type,
+// e.g. "x-service-api-timeout: 3 seconds" requested as time.Duration
+type ParseError struct {
+	// Service name
+	Service string
+	// Property name
+	Property string
+	// Value is a raw property value; multiple values are joined with commas
+	Value string
+	// Type is the name of the requested type
+	Type string
+	// Err is the underlying parser error
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf(
+		"servicectx: cannot parse property %q of service %q with value %q as %s: %v",
+		e.Property,
+		e.Service,
+		e.Value,
+		e.Type,
+		e.Err,
+	)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// converts property values to a given type
+func parseValues(typ reflect.Type, values []string) (interface{}, error) {
+	parsers.RLock()
+	parser, ok := parsers.byType[typ]
+	parsers.RUnlock()
+
+	switch {
+	case ok:
+		return parser(values[0])
+	case reflect.PtrTo(typ).Implements(textUnmarshalerType):
+		ptr := reflect.New(typ)
+		err := ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(values[0]))
+
+		return ptr.Elem().Interface(), err
+	case typ.Kind() == reflect.Slice:
+		result := reflect.MakeSlice(typ, 0, len(values))
+		for _, value := range values {
+			for _, item := range strings.Split(value, ",") {
+				parsed, err := parseValues(typ.Elem(), []string{strings.TrimSpace(item)})
+				if err != nil {
+					return nil, err
+				}
+
+				result = reflect.Append(result, convertValue(parsed, typ.Elem()))
+			}
+		}
+
+		return result.Interface(), nil
+	default:
+		ptr := reflect.New(typ)
+		err := json.Unmarshal([]byte(values[0]), ptr.Interface())
+
+		return ptr.Elem().Interface(), err
+	}
+}
+
+// converts a parsed value to a given type; nil values, like a JSON null parsed into an interface, become zero values
+func convertValue(parsed interface{}, typ reflect.Type) reflect.Value {
+	value := reflect.ValueOf(parsed)
+	if !value.IsValid() {
+		return reflect.Zero(typ)
+	}
+
+	return value.Convert(typ)
+}
+
+// returns a reflect.Type of T, including interface types
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func parseInt[T int8 | int16 | int32 | int64](bitSize int) func(string) (T, error) {
+	return func(value string) (T, error) {
+		result, err := strconv.ParseInt(value, 10, bitSize)
+		return T(result), err
+	}
+}
+
+func parseUint[T uint | uint8 | uint16 | uint32 | uint64](bitSize int) func(string) (T, error) {
+	return func(value string) (T, error) {
+		result, err := strconv.ParseUint(value, 10, bitSize)
+		return T(result), err
+	}
+}
+
+func parseFloat[T float32 | float64](bitSize int) func(string) (T, error) {
+	return func(value string) (T, error) {
+		result, err := strconv.ParseFloat(value, bitSize)
+		return T(result), err
+	}
+}
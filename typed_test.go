@@ -0,0 +1,163 @@
+package servicectx
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	props := New().
+		Set("api", "ratio", "0.75").
+		Set("api", "retries", "3").
+		Set("api", "negative", "-1").
+		Set("api", "since", "2022-03-11T06:39:42Z").
+		Set("api", "url", "http://api:8080/v2").
+		Set("api", "hosts", "a, b,c").
+		Set("api", "ports", "80,443").
+		Set("api", "limits", `{"rps": 100, "burst": 10}`).
+		Set("api", "ip", "127.0.0.1").
+		Set("api", "empty", "").
+		Add("api", "tags", "one,two").
+		Add("api", "tags", "three")
+
+	require.Equal(t, 0.75, Get(props, "api", "ratio", 1.0))
+	require.Equal(t, float32(0.75), Get(props, "api", "ratio", float32(1)))
+	require.Equal(t, uint(3), Get(props, "api", "retries", uint(1)))
+	require.Equal(t, int64(3), Get(props, "api", "retries", int64(1)))
+	require.Equal(t, uint8(1), Get(props, "api", "negative", uint8(1)), "a negative number must not be parsed as unsigned")
+	require.Equal(t, time.Date(2022, 3, 11, 6, 39, 42, 0, time.UTC), Get(props, "api", "since", time.Time{}))
+	require.Equal(t, "api:8080", Get(props, "api", "url", &url.URL{}).Host)
+	require.Equal(t, []string{"a", "b", "c"}, Get(props, "api", "hosts", []string(nil)))
+	require.Equal(t, []int{80, 443}, Get(props, "api", "ports", []int(nil)))
+	require.Equal(t, []string{"one", "two", "three"}, Get(props, "api", "tags", []string(nil)))
+	require.Equal(
+		t,
+		map[string]int{"rps": 100, "burst": 10},
+		Get(props, "api", "limits", map[string]int(nil)),
+	)
+	require.Equal(
+		t,
+		struct{ RPS, Burst int }{100, 10},
+		Get(props, "api", "limits", struct{ RPS, Burst int }{}),
+	)
+	require.Equal(t, net.ParseIP("127.0.0.1"), Get(props, "api", "ip", net.IP(nil)), "encoding.TextUnmarshaler must be supported")
+
+	require.Equal(t, "default", Get(props, "api", "empty", "default"))
+	require.Equal(t, 5, Get(props, "api", "unknown", 5))
+	require.Equal(t, 5, Get(props, "api", "ratio", 5))
+	require.Equal(t, []int{1}, Get(props, "api", "hosts", []int{1}))
+}
+
+type testLevel int
+
+func TestRegisterParser(t *testing.T) {
+	RegisterParser(func(value string) (testLevel, error) {
+		switch strings.ToLower(value) {
+		case "debug":
+			return 1, nil
+		case "info":
+			return 2, nil
+		}
+
+		return 0, errors.New("unknown level")
+	})
+
+	props := New().Set("api", "log-level", "DEBUG").Set("api", "levels", "info,debug")
+	require.Equal(t, testLevel(1), Get(props, "api", "log-level", testLevel(2)))
+	require.Equal(t, []testLevel{2, 1}, Get(props, "api", "levels", []testLevel(nil)))
+
+	props.Set("api", "log-level", "verbose")
+	require.Equal(t, testLevel(2), Get(props, "api", "log-level", testLevel(2)))
+}
+
+func TestLookup(t *testing.T) {
+	props := New().
+		Set("api", "timeout", "3 seconds").
+		Set("api", "retries", "3").
+		Set("api", "enabled", "yes").
+		Set("api", "empty", "")
+
+	value, found := props.Lookup("api", "empty")
+	require.True(t, found)
+	require.Equal(t, "", value)
+
+	_, found = props.Lookup("api", "unknown")
+	require.False(t, found)
+
+	retries, found, err := props.LookupInt("api", "retries")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, 3, retries)
+
+	_, found, err = props.LookupInt("api", "empty")
+	require.NoError(t, err)
+	require.False(t, found, "an empty value must be treated as missing")
+
+	_, found, err = props.LookupBool("api", "enabled")
+	require.True(t, found)
+	require.Error(t, err)
+
+	_, found, err = props.LookupDuration("api", "timeout")
+	require.True(t, found)
+
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(
+		t,
+		&ParseError{
+			Service:  "api",
+			Property: "timeout",
+			Value:    "3 seconds",
+			Type:     "time.Duration",
+			Err:      parseErr.Err,
+		},
+		parseErr,
+	)
+	require.Equal(
+		t,
+		`servicectx: cannot parse property "timeout" of service "api" with value "3 seconds" as time.Duration: `+parseErr.Err.Error(),
+		err.Error(),
+	)
+
+	hosts, found, err := Lookup[[]*url.URL](New().Add("api", "url", "http://a").Add("api", "url", "http://b"), "api", "url")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Len(t, hosts, 2)
+	require.Equal(t, "b", hosts[1].Host)
+}
+
+func TestLookup_Null(t *testing.T) {
+	props := New().Set("a", "any", "null").Set("a", "list", "1,null").Set("a", "ptr", "null")
+
+	require.Nil(t, Get[any](props, "a", "any", "default"))
+
+	list, found, err := Lookup[[]any](props, "a", "list")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, []any{float64(1), nil}, list)
+
+	ptr, found, err := Lookup[*int](props, "a", "ptr")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Nil(t, ptr)
+
+	// a nil value from a custom parser
+	RegisterParser(func(value string) (error, error) {
+		return nil, nil
+	})
+	defer func() {
+		parsers.Lock()
+		delete(parsers.byType, typeOf[error]())
+		parsers.Unlock()
+	}()
+
+	parsedErr, found, err := Lookup[error](props, "a", "any")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Nil(t, parsedErr)
+}